@@ -0,0 +1,298 @@
+// Command winres generates .syso files embedding Windows resources, and is meant to be run by go generate:
+//
+//	//go:generate go run github.com/tc-hib/winres/cmd/winres
+//
+// It loads the winres.json configuration found in the winres directory (see winres.LoadConfig),
+// fills version information from the module's metadata, then writes one object per architecture,
+// named with a target suffix so that "go build" links the proper one:
+//
+//	rsrc_windows_amd64.syso
+//	rsrc_windows_386.syso
+//	...
+//
+// Version information is completed this way:
+//
+//	ProductName and InternalName:      last element of the module path found in go.mod, unless already set
+//	ProductVersion and FileVersion:    output of "git describe --tags", unless set by flags
+//	Timestamp:                         SOURCE_DATE_EPOCH environment variable, if set
+//
+// When the tag is a semantic version, the number of commits since the tag becomes the fourth number of the version,
+// and the rest of the suffix becomes build metadata, e.g. "v1.2.3-4-gabcdef0" gives 1.2.3.4 and "1.2.3+4-gabcdef0".
+//
+// Objects are only written when their content changed, and the output only depends on inputs,
+// so running the command again on a same commit produces identical files.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+)
+
+type options struct {
+	dir            string
+	out            string
+	arch           []winres.Arch
+	productVersion string
+	fileVersion    string
+}
+
+const fromGit = "git"
+
+func main() {
+	opt := options{}
+	var arch string
+	flag.StringVar(&opt.dir, "dir", "winres", "directory containing "+winres.ConfigFileName)
+	flag.StringVar(&opt.out, "out", "rsrc", "prefix of output files")
+	flag.StringVar(&arch, "arch", "amd64,386,arm64", "comma separated list of target architectures")
+	flag.StringVar(&opt.productVersion, "product-version", fromGit, `product version, "git" to use "git describe", or "" to leave it as is`)
+	flag.StringVar(&opt.fileVersion, "file-version", fromGit, `file version, "git" to use "git describe", or "" to leave it as is`)
+	flag.Parse()
+
+	for _, a := range strings.Split(arch, ",") {
+		opt.arch = append(opt.arch, winres.Arch(strings.TrimSpace(a)))
+	}
+
+	if err := generate(opt); err != nil {
+		fmt.Fprintln(os.Stderr, "winres:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(opt options) error {
+	rs, err := winres.LoadConfig(opt.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		rs = &winres.ResourceSet{}
+	} else if err != nil {
+		return err
+	}
+
+	vi, err := readVersionInfo(rs)
+	if err != nil {
+		return err
+	}
+	if err = fillVersionInfo(vi, opt); err != nil {
+		return err
+	}
+	if vi.ProductVersion != [4]uint16{} || vi.FileVersion != [4]uint16{} || len(vi.LangIDs()) > 0 {
+		// Remove previous translations, as SetVersionInfo only sets translations found in vi
+		deleteType(rs, winres.RT_VERSION)
+		rs.SetVersionInfo(*vi)
+	}
+
+	for _, arch := range opt.arch {
+		buf := &bytes.Buffer{}
+		if err = rs.WriteObject(buf, arch); err != nil {
+			return fmt.Errorf("%s: %w", arch, err)
+		}
+		if err = writeIfChanged(opt.out+"_windows_"+string(arch)+".syso", buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readVersionInfo returns the version info loaded from the configuration, or an empty one.
+func readVersionInfo(rs *winres.ResourceSet) (*version.Info, error) {
+	translations := map[uint16]*version.Info{}
+	var err error
+	rs.WalkType(winres.RT_VERSION, func(resID winres.Identifier, langID uint16, data []byte) bool {
+		var vi *version.Info
+		vi, err = version.FromBytes(data)
+		translations[langID] = vi
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version.MergeTranslations(translations), nil
+}
+
+func deleteType(rs *winres.ResourceSet, typeID winres.Identifier) {
+	type key struct {
+		resID  winres.Identifier
+		langID uint16
+	}
+	var keys []key
+	rs.WalkType(typeID, func(resID winres.Identifier, langID uint16, _ []byte) bool {
+		keys = append(keys, key{resID, langID})
+		return true
+	})
+	for _, k := range keys {
+		rs.Set(typeID, k.resID, k.langID, nil)
+	}
+}
+
+func fillVersionInfo(vi *version.Info, opt options) error {
+	modPath, err := readModulePath(".")
+	if err != nil {
+		return err
+	}
+	name := modPath[strings.LastIndex(modPath, "/")+1:]
+	langIDs := vi.LangIDs()
+	if len(langIDs) == 0 {
+		langIDs = []uint16{version.LangNeutral}
+	}
+	for _, langID := range langIDs {
+		if vi.Get(langID, version.ProductName) == "" {
+			vi.Set(langID, version.ProductName, name)
+		}
+		if vi.Get(langID, version.InternalName) == "" {
+			vi.Set(langID, version.InternalName, name)
+		}
+	}
+
+	if opt.productVersion == fromGit || opt.fileVersion == fromGit {
+		setGitVersion(vi, gitDescribe(), opt.productVersion == fromGit, opt.fileVersion == fromGit)
+	}
+	if opt.productVersion != "" && opt.productVersion != fromGit {
+		vi.SetProductVersion(opt.productVersion)
+	}
+	if opt.fileVersion != "" && opt.fileVersion != fromGit {
+		vi.SetFileVersion(opt.fileVersion)
+	}
+
+	ts, err := sourceDateEpoch()
+	if err != nil {
+		return err
+	}
+	if !ts.IsZero() {
+		vi.Timestamp = ts
+	}
+
+	return nil
+}
+
+// readModulePath searches for a go.mod file in dir and its parents, and returns the module path.
+func readModulePath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		f, err := os.Open(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			s := bufio.NewScanner(f)
+			for s.Scan() {
+				fields := strings.Fields(s.Text())
+				if len(fields) >= 2 && fields[0] == "module" {
+					return strings.Trim(fields[1], "\"`"), nil
+				}
+			}
+			return "", errors.New("no module path in " + f.Name())
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// gitDescribe returns a version string such as "v1.2.3" or "v1.2.3-4-gabcdef0".
+//
+// If there is no tag, it returns "0.0.0-0-gabcdef0",
+// and if git is not available, it returns an empty string.
+func gitDescribe() string {
+	out, err := exec.Command("git", "describe", "--tags").Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	out, err = exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err == nil {
+		return "0.0.0-0-g" + strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// setGitVersion sets the product version, the file version, or both, from the output of gitDescribe.
+//
+// When the tag is a semantic version, it is set as in version.Info.SetFromSemver.
+// Otherwise, the output is set as is.
+func setGitVersion(vi *version.Info, describe string, product bool, file bool) {
+	if describe == "" {
+		return
+	}
+	semver, commits := parseGitDescribe(describe)
+	git := version.Info{}
+	if git.SetFromSemver(semver, commits) != nil {
+		if product {
+			vi.SetProductVersion(describe)
+		}
+		if file {
+			vi.SetFileVersion(describe)
+		}
+		return
+	}
+	if product && file {
+		vi.SetFromSemver(semver, commits)
+		return
+	}
+	if product {
+		vi.SetProductVersion(git.Get(version.LangNeutral, version.ProductVersion))
+	}
+	if file {
+		vi.SetFileVersion(git.Get(version.LangNeutral, version.FileVersion))
+	}
+}
+
+// parseGitDescribe splits the output of "git describe --tags" into a tag and the number of commits since the tag.
+//
+// The number of commits and the abbreviated hash are kept as build metadata:
+//
+//	v1.2.3-4-gabcdef -> v1.2.3+4-gabcdef, 4
+//
+// A tag without a suffix is returned as is, with 0 commits.
+func parseGitDescribe(describe string) (string, uint16) {
+	i := strings.LastIndex(describe, "-g")
+	if i < 0 || i+2 == len(describe) || strings.Trim(describe[i+2:], "0123456789abcdef") != "" {
+		return describe, 0
+	}
+	j := strings.LastIndexByte(describe[:i], '-')
+	if j < 0 {
+		return describe, 0
+	}
+	commits, err := strconv.ParseUint(describe[j+1:i], 10, 16)
+	if err != nil {
+		return describe, 0
+	}
+	return describe[:j] + "+" + describe[j+1:], uint16(commits)
+}
+
+// sourceDateEpoch returns the time defined by the SOURCE_DATE_EPOCH environment variable,
+// or a zero time when it is not defined.
+//
+// https://reproducible-builds.org/specs/source-date-epoch/
+func sourceDateEpoch() (time.Time, error) {
+	s := os.Getenv("SOURCE_DATE_EPOCH")
+	if s == "" {
+		return time.Time{}, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid SOURCE_DATE_EPOCH")
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// writeIfChanged writes a file only if its content is different, so that its modification time doesn't change needlessly.
+func writeIfChanged(name string, data []byte) error {
+	old, err := os.ReadFile(name)
+	if err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	return os.WriteFile(name, data, 0666)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+)
+
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+	os.WriteFile("go.mod", []byte("module example.com/some/app\n\ngo 1.19\n"), 0666)
+	os.Mkdir("winres", 0777)
+	os.WriteFile(filepath.Join("winres", winres.ConfigFileName), []byte(`{
+  "RT_VERSION": {"#1": {"0000": {"info": {"040C": {"ProductName": "Application"}}}}},
+  "RT_MANIFEST": {"#1": {"0409": {}}}
+}`), 0666)
+	t.Setenv("SOURCE_DATE_EPOCH", "1600000000")
+
+	opt := options{
+		dir:            "winres",
+		out:            "rsrc",
+		arch:           []winres.Arch{winres.ArchAMD64, winres.ArchI386},
+		productVersion: "v1.2.3-rc1",
+		fileVersion:    "1.2.3.4",
+	}
+	if err := generate(opt); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile("rsrc_windows_amd64.syso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat("rsrc_windows_386.syso"); err != nil {
+		t.Fatal(err)
+	}
+	stat, _ := os.Stat("rsrc_windows_amd64.syso")
+
+	time.Sleep(10 * time.Millisecond)
+	if err = generate(opt); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := os.ReadFile("rsrc_windows_amd64.syso")
+	if !bytes.Equal(first, second) {
+		t.Error("output should be identical")
+	}
+	if stat2, _ := os.Stat("rsrc_windows_amd64.syso"); !stat2.ModTime().Equal(stat.ModTime()) {
+		t.Error("output should not have been written again")
+	}
+
+	rs := &winres.ResourceSet{}
+	opt.fileVersion = ""
+	vi, _ := readVersionInfo(rs)
+	if err = fillVersionInfo(vi, opt); err != nil {
+		t.Fatal(err)
+	}
+	if vi.ProductVersion != [4]uint16{1, 2, 3} || vi.Get(0, version.ProductName) != "app" || vi.Get(0, version.InternalName) != "app" {
+		t.Fail()
+	}
+	if !vi.Timestamp.Equal(time.Unix(1600000000, 0)) {
+		t.Fail()
+	}
+}
+
+func TestGenerate_Err(t *testing.T) {
+	dir := t.TempDir()
+	chdir(t, dir)
+
+	if generate(options{dir: "winres"}) == nil {
+		t.Error("go.mod should be required")
+	}
+
+	os.WriteFile("go.mod", []byte("go 1.19\n"), 0666)
+	if generate(options{dir: "winres"}) == nil {
+		t.Error("module path should be required")
+	}
+
+	os.WriteFile("go.mod", []byte("module app\n"), 0666)
+	if generate(options{dir: "winres", arch: []winres.Arch{"*"}}) == nil {
+		t.Error("architecture should be checked")
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if generate(options{dir: "winres"}) == nil {
+		t.Error("SOURCE_DATE_EPOCH should be checked")
+	}
+}
+
+func Test_readModulePath(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("// comment\nmodule \"example.com/quoted\"\n"), 0666)
+	sub := filepath.Join(dir, "sub", "dir")
+	os.MkdirAll(sub, 0777)
+
+	path, err := readModulePath(sub)
+	if err != nil || path != "example.com/quoted" {
+		t.Error(path, err)
+	}
+}
+
+func Test_parseGitDescribe(t *testing.T) {
+	tests := []struct {
+		describe string
+		semver   string
+		commits  uint16
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3-4-gabcdef0", "v1.2.3+4-gabcdef0", 4},
+		{"v1.2.3-rc.1-12-g0123456", "v1.2.3-rc.1+12-g0123456", 12},
+		{"0.0.0-0-gabcdef0", "0.0.0+0-gabcdef0", 0},
+		{"v1.0.0-gamma", "v1.0.0-gamma", 0},
+		{"v1.0.0-beta-g", "v1.0.0-beta-g", 0},
+		{"release-gabc", "release-gabc", 0},
+		{"v1.2.3-65536-gabcdef0", "v1.2.3-65536-gabcdef0", 0},
+	}
+	for _, tt := range tests {
+		semver, commits := parseGitDescribe(tt.describe)
+		if semver != tt.semver || commits != tt.commits {
+			t.Errorf("parseGitDescribe(%q) = %q, %d, want %q, %d", tt.describe, semver, commits, tt.semver, tt.commits)
+		}
+	}
+}
+
+func Test_setGitVersion(t *testing.T) {
+	vi := &version.Info{}
+	setGitVersion(vi, "v1.2.3-4-gabcdef0", true, true)
+	if vi.FileVersion != [4]uint16{1, 2, 3, 4} || vi.ProductVersion != [4]uint16{1, 2, 3, 4} ||
+		vi.Get(version.LangNeutral, version.ProductVersion) != "1.2.3+4-gabcdef0" ||
+		vi.Get(version.LangNeutral, version.PrivateBuild) != "4-gabcdef0" {
+		t.Errorf("%+v", vi)
+	}
+
+	vi = &version.Info{}
+	setGitVersion(vi, "v1.2.3-4-gabcdef0", false, true)
+	if vi.FileVersion != [4]uint16{1, 2, 3, 4} || vi.ProductVersion != [4]uint16{} ||
+		vi.Get(version.LangNeutral, version.FileVersion) != "1.2.3.4" || vi.Get(version.LangNeutral, version.ProductVersion) != "" {
+		t.Errorf("%+v", vi)
+	}
+
+	vi = &version.Info{}
+	setGitVersion(vi, "release", true, true)
+	if vi.Get(version.LangNeutral, version.ProductVersion) != "release" || vi.FileVersion != [4]uint16{} {
+		t.Errorf("%+v", vi)
+	}
+}
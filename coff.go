@@ -33,18 +33,11 @@ func writeObject(w io.Writer, r *ResourceSet, arch Arch) error {
 	section.PointerToRelocations = section.PointerToRawData + section.SizeOfRawData
 	section.NumberOfRelocations = uint16(r.numDataEntries())
 
-	switch arch {
-	case ArchI386:
-		file.Machine = pe.IMAGE_FILE_MACHINE_I386
-	case ArchAMD64:
-		file.Machine = pe.IMAGE_FILE_MACHINE_AMD64
-	case ArchARM:
-		file.Machine = pe.IMAGE_FILE_MACHINE_ARMNT
-	case ArchARM64:
-		file.Machine = pe.IMAGE_FILE_MACHINE_ARM64
-	default:
-		return errors.New(errUnknownArch)
+	machine, err := machineType(arch)
+	if err != nil {
+		return err
 	}
+	file.Machine = machine
 	file.PointerToSymbolTable = section.PointerToRelocations + uint32(section.NumberOfRelocations)*sizeOfReloc
 
 	if err := binary.Write(w, binary.LittleEndian, file); err != nil {
@@ -70,6 +63,20 @@ func writeObject(w io.Writer, r *ResourceSet, arch Arch) error {
 	return nil
 }
 
+func machineType(arch Arch) (uint16, error) {
+	switch arch {
+	case ArchI386:
+		return pe.IMAGE_FILE_MACHINE_I386, nil
+	case ArchAMD64:
+		return pe.IMAGE_FILE_MACHINE_AMD64, nil
+	case ArchARM:
+		return pe.IMAGE_FILE_MACHINE_ARMNT, nil
+	case ArchARM64:
+		return pe.IMAGE_FILE_MACHINE_ARM64, nil
+	}
+	return 0, errors.New(errUnknownArch)
+}
+
 // https://docs.microsoft.com/en-us/windows/win32/debug/pe-format#type-indicators
 
 const (
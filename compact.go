@@ -0,0 +1,218 @@
+package winres
+
+import (
+	"errors"
+	"sort"
+)
+
+// Compact removes icon and cursor images that are not referenced by any group.
+//
+// Replacing a group with SetIcon or SetCursor, or deleting a group with Set,
+// leaves the previous images in the set. Compact is the way to get rid of them.
+//
+// When renumber is true, remaining images get new IDs, starting from 1 without gaps,
+// and groups are updated accordingly.
+//
+// Compact returns an error, without modifying the set, if a group cannot be read or parsed.
+func (rs *ResourceSet) Compact(renumber bool) error {
+	icons, iconGroups, err := rs.usedImageIDs(RT_GROUP_ICON, nil)
+	if err != nil {
+		return err
+	}
+	cursors, cursorGroups, err := rs.usedImageIDs(RT_GROUP_CURSOR, nil)
+	if err != nil {
+		return err
+	}
+
+	rs.dropUnusedImages(RT_ICON, icons)
+	rs.dropUnusedImages(RT_CURSOR, cursors)
+
+	if !renumber {
+		return nil
+	}
+
+	rs.lastIconID = rs.renumberImages(RT_ICON, icons, iconGroups)
+	rs.lastCursorID = rs.renumberImages(RT_CURSOR, cursors, cursorGroups)
+
+	return nil
+}
+
+// deleteGroup deletes an icon or cursor group in every language,
+// as well as the images that no other group references.
+func (rs *ResourceSet) deleteGroup(groupTypeID, imageTypeID ID, resID Identifier) error {
+	te := rs.Types[groupTypeID]
+	if te == nil || te.Resources[resID] == nil {
+		return errors.New(errGroupNotFound)
+	}
+
+	images := make(map[uint16]struct{})
+	for _, de := range te.Resources[resID].Data {
+		data, err := de.readData()
+		if err != nil {
+			return err
+		}
+		ids, err := groupImageIDs(data)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			images[id] = struct{}{}
+		}
+	}
+
+	used, _, err := rs.usedImageIDs(groupTypeID, resID)
+	if err != nil {
+		return err
+	}
+
+	rs.deleteResource(groupTypeID, resID)
+	for id := range images {
+		if _, ok := used[id]; !ok {
+			rs.deleteResource(imageTypeID, ID(id))
+		}
+	}
+
+	return nil
+}
+
+// usedImageIDs returns the set of image IDs referenced by every group of a type, in every language.
+//
+// It also returns the data of each group, which is only read once, as it may come from a file.
+//
+// The group identified by except is ignored. It may be nil.
+func (rs *ResourceSet) usedImageIDs(groupTypeID ID, except Identifier) (map[uint16]struct{}, map[*DataEntry][]byte, error) {
+	used := make(map[uint16]struct{})
+	groups := make(map[*DataEntry][]byte)
+
+	te := rs.Types[groupTypeID]
+	if te == nil {
+		return used, groups, nil
+	}
+
+	for ident, re := range te.Resources {
+		if ident == except {
+			continue
+		}
+		for _, de := range re.Data {
+			data, err := de.readData()
+			if err != nil {
+				return nil, nil, err
+			}
+			ids, err := groupImageIDs(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, id := range ids {
+				used[id] = struct{}{}
+			}
+			groups[de] = data
+		}
+	}
+
+	return used, groups, nil
+}
+
+// dropUnusedImages removes images that are not in the used set.
+//
+// Images identified by a Name are always removed, as a group can only reference IDs.
+func (rs *ResourceSet) dropUnusedImages(imageTypeID ID, used map[uint16]struct{}) {
+	te := rs.Types[imageTypeID]
+	if te == nil {
+		return
+	}
+
+	var unused []Identifier
+	for ident := range te.Resources {
+		id, ok := ident.(ID)
+		if !ok {
+			unused = append(unused, ident)
+			continue
+		}
+		if _, ok = used[uint16(id)]; !ok {
+			unused = append(unused, ident)
+		}
+	}
+
+	for _, ident := range unused {
+		rs.deleteResource(imageTypeID, ident)
+	}
+}
+
+// renumberImages gives images new consecutive IDs, following the order of their current IDs,
+// and updates every group accordingly.
+//
+// groups are the entries and data returned by usedImageIDs.
+//
+// It returns the last ID in use.
+func (rs *ResourceSet) renumberImages(imageTypeID ID, used map[uint16]struct{}, groups map[*DataEntry][]byte) uint16 {
+	ids := make([]int, 0, len(used))
+	for id := range used {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	newIDs := make(map[uint16]uint16, len(ids))
+	for i, id := range ids {
+		newIDs[uint16(id)] = uint16(i + 1)
+	}
+
+	if te := rs.Types[imageTypeID]; te != nil {
+		resources := make(map[Identifier]*ResourceEntry, len(te.Resources))
+		for ident, re := range te.Resources {
+			resources[ID(newIDs[uint16(ident.(ID))])] = re
+		}
+		te.Resources = resources
+		te.OrderedKeys = nil
+	}
+
+	for de, data := range groups {
+		*de = DataEntry{Data: renumberGroup(data, newIDs)}
+	}
+
+	return uint16(len(ids))
+}
+
+// Icon groups and cursor groups share the same binary layout:
+// a header with a count of entries, followed by entries that end with an image ID.
+const (
+	sizeOfGroupHeader   = 6
+	sizeOfGroupResEntry = 14
+	groupResEntryIDPos  = 12
+)
+
+// groupImageIDs returns the image IDs referenced by an RT_GROUP_ICON or an RT_GROUP_CURSOR resource.
+func groupImageIDs(data []byte) ([]uint16, error) {
+	if len(data) < sizeOfGroupHeader {
+		return nil, errors.New(errInvalidGroup)
+	}
+
+	count := int(data[5])<<8 | int(data[4])
+	if len(data) < sizeOfGroupHeader+count*sizeOfGroupResEntry {
+		return nil, errors.New(errInvalidGroup)
+	}
+
+	ids := make([]uint16, count)
+	for i := range ids {
+		p := sizeOfGroupHeader + i*sizeOfGroupResEntry + groupResEntryIDPos
+		ids[i] = uint16(data[p+1])<<8 | uint16(data[p])
+	}
+
+	return ids, nil
+}
+
+// renumberGroup returns a copy of a group resource with image IDs replaced.
+//
+// The group must have been validated by groupImageIDs.
+func renumberGroup(data []byte, newIDs map[uint16]uint16) []byte {
+	data = append([]byte{}, data...)
+
+	count := int(data[5])<<8 | int(data[4])
+	for i := 0; i < count; i++ {
+		p := sizeOfGroupHeader + i*sizeOfGroupResEntry + groupResEntryIDPos
+		id := newIDs[uint16(data[p+1])<<8|uint16(data[p])]
+		data[p] = uint8(id)
+		data[p+1] = uint8(id >> 8)
+	}
+
+	return data
+}
@@ -0,0 +1,238 @@
+package winres
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func newTestIcon(t *testing.T, sizes ...int) *Icon {
+	var images []image.Image
+	for _, s := range sizes {
+		images = append(images, image.NewNRGBA(image.Rect(0, 0, s, s)))
+	}
+	icon, err := NewIconFromImages(images)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return icon
+}
+
+func newTestCursor(t *testing.T, sizes ...int) *Cursor {
+	var images []CursorImage
+	for _, s := range sizes {
+		images = append(images, CursorImage{Image: image.NewNRGBA(image.Rect(0, 0, s, s))})
+	}
+	cursor, err := NewCursorFromImages(images)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cursor
+}
+
+func resourceIDs(rs *ResourceSet, typeID Identifier) []Identifier {
+	var ids []Identifier
+	rs.WalkType(typeID, func(resID Identifier, langID uint16, data []byte) bool {
+		ids = append(ids, resID)
+		return true
+	})
+	return ids
+}
+
+func TestResourceSet_DeleteIcon(t *testing.T) {
+	rs := ResourceSet{}
+	shared := newTestIcon(t, 32, 16)
+
+	if err := rs.SetIcon(ID(1), newTestIcon(t, 48)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.SetIconTranslation(ID(2), 0x409, shared); err != nil {
+		t.Fatal(err)
+	}
+	// Same images, referenced by a second group
+	rs.Set(RT_GROUP_ICON, ID(3), 0, rs.Get(RT_GROUP_ICON, ID(2), 0x409))
+
+	if err := rs.DeleteIcon(ID(2)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_ICON), []Identifier{ID(1), ID(2), ID(3)}) {
+		t.Error("shared images should have been kept")
+	}
+
+	if err := rs.DeleteIcon(ID(3)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_ICON), []Identifier{ID(1)}) {
+		t.Error("orphaned images should have been removed")
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_GROUP_ICON), []Identifier{ID(1)}) {
+		t.Fail()
+	}
+
+	if err := rs.DeleteIcon(ID(1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Types) != 0 {
+		t.Fail()
+	}
+
+	err := rs.DeleteIcon(ID(1))
+	if err == nil || err.Error() != errGroupNotFound {
+		t.Fail()
+	}
+}
+
+func TestResourceSet_DeleteIcon_ErrInvalidGroup(t *testing.T) {
+	rs := ResourceSet{}
+	rs.Set(RT_GROUP_ICON, ID(1), 0, []byte{0, 0, 1, 0, 1, 0})
+
+	err := rs.DeleteIcon(ID(1))
+	if err == nil || err.Error() != errInvalidGroup {
+		t.Fail()
+	}
+	if rs.Get(RT_GROUP_ICON, ID(1), 0) == nil {
+		t.Error("group should not have been deleted")
+	}
+}
+
+func TestResourceSet_DeleteCursor(t *testing.T) {
+	rs := ResourceSet{}
+
+	if err := rs.SetCursor(Name("ARROW"), newTestCursor(t, 32, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.SetCursor(Name("HAND"), newTestCursor(t, 32)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rs.DeleteCursor(Name("ARROW")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_CURSOR), []Identifier{ID(3)}) {
+		t.Fail()
+	}
+	if _, err := rs.GetCursor(Name("HAND")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResourceSet_Compact(t *testing.T) {
+	rs := ResourceSet{}
+
+	rs.SetIcon(ID(1), newTestIcon(t, 48, 32))
+	rs.SetIcon(ID(1), newTestIcon(t, 64, 16))
+	rs.SetIconTranslation(ID(1), 0x40C, newTestIcon(t, 24))
+	rs.SetCursor(ID(1), newTestCursor(t, 32))
+	rs.SetCursor(ID(1), newTestCursor(t, 16))
+	rs.Set(RT_ICON, Name("STRAY"), 0, []byte{1})
+
+	if err := rs.Compact(false); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_ICON), []Identifier{ID(3), ID(4), ID(5)}) {
+		t.Error(resourceIDs(&rs, RT_ICON))
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_CURSOR), []Identifier{ID(2)}) {
+		t.Error(resourceIDs(&rs, RT_CURSOR))
+	}
+
+	icon, err := rs.GetIconTranslation(ID(1), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.Compact(true); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_ICON), []Identifier{ID(1), ID(2), ID(3)}) {
+		t.Error(resourceIDs(&rs, RT_ICON))
+	}
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_CURSOR), []Identifier{ID(1)}) {
+		t.Error(resourceIDs(&rs, RT_CURSOR))
+	}
+	if rs.lastIconID != 3 || rs.lastCursorID != 1 {
+		t.Fail()
+	}
+
+	renumbered, err := rs.GetIconTranslation(ID(1), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(icon, renumbered) {
+		t.Error("icon should not have changed")
+	}
+	if _, err = rs.GetIconTranslation(ID(1), 0x40C); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rs.GetCursor(ID(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	rs.SetIcon(ID(2), newTestIcon(t, 16))
+	if !reflect.DeepEqual(resourceIDs(&rs, RT_ICON), []Identifier{ID(1), ID(2), ID(3), ID(4)}) {
+		t.Error(resourceIDs(&rs, RT_ICON))
+	}
+}
+
+func TestResourceSet_Compact_ErrInvalidGroup(t *testing.T) {
+	rs := ResourceSet{}
+	rs.Set(RT_ICON, ID(1), 0, []byte{1})
+	rs.Set(RT_GROUP_CURSOR, ID(1), 0, []byte{0, 0, 2})
+
+	err := rs.Compact(true)
+	if err == nil || err.Error() != errInvalidGroup {
+		t.Fail()
+	}
+	if rs.Get(RT_ICON, ID(1), 0) == nil {
+		t.Error("set should not have been modified")
+	}
+}
+
+func TestResourceSet_Compact_Lazy(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIcon(ID(1), newTestIcon(t, 48, 32))
+	rs.SetIcon(ID(1), newTestIcon(t, 16))
+	rs.SetIcon(ID(2), newTestIcon(t, 24))
+
+	dll := &bytes.Buffer{}
+	if err := rs.WriteDLL(dll, ArchAMD64, DLLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	src := &failingReaderAt{r: bytes.NewReader(dll.Bytes())}
+	lazy, err := LoadFromEXELazy(src, int64(dll.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read errors are reported as is, and the set is not modified
+	src.fail = true
+	if err = lazy.Compact(true); err == nil || err.Error() != errRead {
+		t.Error(err)
+	}
+	if err = lazy.DeleteIcon(ID(1)); err == nil || err.Error() != errRead {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(lazy, RT_ICON), []Identifier{ID(1), ID(2), ID(3), ID(4)}) {
+		t.Error(resourceIDs(lazy, RT_ICON))
+	}
+	if !reflect.DeepEqual(resourceIDs(lazy, RT_GROUP_ICON), []Identifier{ID(1), ID(2)}) {
+		t.Error(resourceIDs(lazy, RT_GROUP_ICON))
+	}
+
+	src.fail = false
+	if err = lazy.Compact(true); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(lazy, RT_ICON), []Identifier{ID(1), ID(2)}) {
+		t.Error(resourceIDs(lazy, RT_ICON))
+	}
+	if _, err = lazy.GetIcon(ID(2)); err != nil {
+		t.Error(err)
+	}
+	if err = lazy.DeleteIcon(ID(1)); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(resourceIDs(lazy, RT_ICON), []Identifier{ID(2)}) {
+		t.Error(resourceIDs(lazy, RT_ICON))
+	}
+}
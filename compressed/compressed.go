@@ -0,0 +1,174 @@
+// Package compressed stores resource data compressed, behind a small self-describing header,
+// and loads it back at run time.
+//
+// A program embeds compressed data with winres:
+//
+//	rs.SetCompressed(winres.RT_RCDATA, winres.Name("BUNDLE"), 0, data, compressed.Deflate)
+//
+// and reads it back with:
+//
+//	data, err := compressed.Load(compressed.RCDATA, "BUNDLE")
+//
+// Deflate is always available.
+// Other methods, such as Zstd, need to be registered, both where data is encoded and where it is loaded,
+// as with archive/zip. For example, with github.com/klauspost/compress/zstd:
+//
+//	compressed.RegisterCompressor(compressed.Zstd, func(w io.Writer) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w)
+//	})
+//	compressed.RegisterDecompressor(compressed.Zstd, func(r io.Reader) io.ReadCloser {
+//		d, _ := zstd.NewReader(r) // only fails with invalid options
+//		return d.IOReadCloser()
+//	})
+//
+// The header is 16 bytes long, little endian:
+//
+//	magic            [3]byte // "WRZ"
+//	version          uint8   // 1
+//	method           uint8   // Deflate, Zstd, ...
+//	reserved         [3]byte // 0
+//	uncompressedSize uint64
+package compressed
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+)
+
+// Method identifies a compression method.
+type Method uint8
+
+const (
+	// Deflate is raw deflate, as in compress/flate.
+	Deflate Method = 1
+	// Zstd is Zstandard.
+	// It has to be registered with RegisterCompressor and RegisterDecompressor.
+	Zstd Method = 2
+)
+
+// A Compressor returns a new compressing writer, writing to w.
+// The WriteCloser's Close method must be used to flush pending data to w.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// A Decompressor returns a new decompressing reader, reading from r.
+// The ReadCloser's Close method must be used to release associated resources.
+type Decompressor func(r io.Reader) io.ReadCloser
+
+var (
+	compressors   sync.Map // map[Method]Compressor
+	decompressors sync.Map // map[Method]Decompressor
+)
+
+func init() {
+	compressors.Store(Deflate, Compressor(func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestCompression)
+	}))
+	decompressors.Store(Deflate, Decompressor(flate.NewReader))
+}
+
+// RegisterCompressor registers or overrides a compressor for a method.
+func RegisterCompressor(method Method, comp Compressor) {
+	compressors.Store(method, comp)
+}
+
+// RegisterDecompressor registers or overrides a decompressor for a method.
+func RegisterDecompressor(method Method, dcomp Decompressor) {
+	decompressors.Store(method, dcomp)
+}
+
+const (
+	magic         = "WRZ"
+	formatVersion = 1
+	sizeOfHeader  = 16
+
+	// maxPrealloc limits the memory allocated before decompressing, in case the header is wrong.
+	maxPrealloc = 64 << 20
+)
+
+type header struct {
+	Magic            [3]byte
+	Version          uint8
+	Method           Method
+	Reserved         [3]byte
+	UncompressedSize uint64
+}
+
+// IsCompressed tells if data starts with a compressed resource header.
+func IsCompressed(data []byte) bool {
+	return len(data) >= sizeOfHeader && string(data[:len(magic)]) == magic
+}
+
+// Encode compresses data with the given method, and prepends a header.
+func Encode(data []byte, method Method) ([]byte, error) {
+	comp, ok := compressors.Load(method)
+	if !ok {
+		return nil, errors.New(errUnknownMethod)
+	}
+
+	buf := &bytes.Buffer{}
+	h := header{
+		Version:          formatVersion,
+		Method:           method,
+		UncompressedSize: uint64(len(data)),
+	}
+	copy(h.Magic[:], magic)
+	// bytes.Buffer.Write never returns an error.
+	binary.Write(buf, binary.LittleEndian, &h)
+
+	w, err := comp.(Compressor)(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode decompresses data that was encoded with Encode.
+func Decode(data []byte) ([]byte, error) {
+	if !IsCompressed(data) {
+		return nil, errors.New(errNotCompressed)
+	}
+	h := header{}
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, &h)
+	if h.Version != formatVersion {
+		return nil, errors.New(errUnknownVersion)
+	}
+	if h.UncompressedSize >= math.MaxInt64 {
+		return nil, errors.New(errSizeTooBig)
+	}
+	dcomp, ok := decompressors.Load(h.Method)
+	if !ok {
+		return nil, errors.New(errUnknownMethod)
+	}
+
+	prealloc := h.UncompressedSize
+	if prealloc > maxPrealloc {
+		prealloc = maxPrealloc
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, prealloc))
+
+	r := dcomp.(Decompressor)(bytes.NewReader(data[sizeOfHeader:]))
+	defer r.Close()
+	// Read one more byte than expected, to detect data that is too long
+	n, err := io.Copy(buf, io.LimitReader(r, int64(h.UncompressedSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(n) != h.UncompressedSize {
+		return nil, errors.New(errSizeMismatch)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+package compressed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestEncode(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"key": "value"}, `), 1000)
+
+	enc, err := Encode(data, Deflate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsCompressed(enc) || len(enc) >= len(data)/10 {
+		t.Errorf("%d bytes", len(enc))
+	}
+	if string(enc[:5]) != "WRZ\x01\x01" || binary.LittleEndian.Uint64(enc[8:]) != uint64(len(data)) {
+		t.Errorf("wrong header: % X", enc[:sizeOfHeader])
+	}
+
+	dec, err := Decode(enc)
+	if err != nil || !bytes.Equal(dec, data) {
+		t.Error(err)
+	}
+
+	enc, err = Encode(nil, Deflate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err = Decode(enc)
+	if err != nil || len(dec) != 0 {
+		t.Error(err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const method Method = 42
+
+	if _, err := Encode([]byte("data"), method); err == nil || err.Error() != errUnknownMethod {
+		t.Error(err)
+	}
+
+	RegisterCompressor(method, func(w io.Writer) (io.WriteCloser, error) {
+		return nopWriteCloser{w}, nil
+	})
+	enc, err := Encode([]byte("data"), method)
+	if err != nil || string(enc[sizeOfHeader:]) != "data" || Method(enc[4]) != method {
+		t.Fatal(err)
+	}
+	if _, err = Decode(enc); err == nil || err.Error() != errUnknownMethod {
+		t.Error(err)
+	}
+
+	RegisterDecompressor(method, func(r io.Reader) io.ReadCloser {
+		return io.NopCloser(r)
+	})
+	dec, err := Decode(enc)
+	if err != nil || string(dec) != "data" {
+		t.Error(err)
+	}
+
+	RegisterCompressor(method, func(w io.Writer) (io.WriteCloser, error) {
+		return nil, errors.New("expected error")
+	})
+	if _, err = Encode([]byte("data"), method); err == nil || err.Error() != "expected error" {
+		t.Error(err)
+	}
+}
+
+func TestDecode_Err(t *testing.T) {
+	valid, _ := Encode([]byte("some data"), Deflate)
+	corrupt := func(offset int, b ...byte) []byte {
+		data := append([]byte{}, valid...)
+		copy(data[offset:], b)
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "nil", data: nil, wantErr: errNotCompressed},
+		{name: "short", data: valid[:sizeOfHeader-1], wantErr: errNotCompressed},
+		{name: "magic", data: corrupt(0, 'W', 'R', 'X'), wantErr: errNotCompressed},
+		{name: "version", data: corrupt(3, 2), wantErr: errUnknownVersion},
+		{name: "method", data: corrupt(4, 0), wantErr: errUnknownMethod},
+		{name: "tooBig", data: corrupt(8, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F), wantErr: errSizeTooBig},
+		{name: "shorter", data: corrupt(8, 8), wantErr: errSizeMismatch},
+		{name: "longer", data: corrupt(8, 10), wantErr: errSizeMismatch},
+		{name: "truncated", data: valid[:len(valid)-2], wantErr: "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Decode(tt.data)
+			if data != nil || err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_decodeResource(t *testing.T) {
+	raw := []byte("raw data")
+	data, err := decodeResource(raw)
+	if err != nil || !bytes.Equal(data, raw) || &data[0] == &raw[0] {
+		t.Error("raw data should be copied")
+	}
+
+	enc, _ := Encode(raw, Deflate)
+	data, err = decodeResource(enc)
+	if err != nil || !bytes.Equal(data, raw) {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,11 @@
+package compressed
+
+const (
+	errNotCompressed     = "not compressed resource data"
+	errUnknownVersion    = "unknown compressed resource version"
+	errUnknownMethod     = "unknown compression method"
+	errSizeMismatch      = "decompressed size doesn't match the header"
+	errSizeTooBig        = "uncompressed size too big"
+	errResourceNotFound  = "resource not found"
+	errUnsupportedSystem = "resources can only be loaded on Windows"
+)
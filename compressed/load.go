@@ -0,0 +1,39 @@
+package compressed
+
+// RCDATA is the name of the RT_RCDATA resource type, as FindResource understands it.
+const RCDATA = "#10"
+
+// Load loads a resource from the executable of the current process, and decompresses it if needed.
+//
+// typeName and resName are names as FindResource understands them: either a name, or "#" followed by a decimal ID,
+// such as RCDATA.
+// The resource language is chosen by Windows, as with FindResource.
+//
+// Data that doesn't start with a compressed resource header is returned as is.
+//
+// Load only works on Windows.
+func Load(typeName, resName string) ([]byte, error) {
+	data, err := findResource(typeName, resName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResource(data)
+}
+
+// LoadLang is like Load, for a specific language ID.
+func LoadLang(typeName, resName string, langID uint16) ([]byte, error) {
+	data, err := findResource(typeName, resName, &langID)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResource(data)
+}
+
+// decodeResource decompresses data if needed.
+// data may point to read-only memory, so it is never returned as is.
+func decodeResource(data []byte) ([]byte, error) {
+	if !IsCompressed(data) {
+		return append([]byte{}, data...), nil
+	}
+	return Decode(data)
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package compressed
+
+import "errors"
+
+func findResource(typeName, resName string, langID *uint16) ([]byte, error) {
+	return nil, errors.New(errUnsupportedSystem)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package compressed
+
+import "testing"
+
+func TestLoad_Unsupported(t *testing.T) {
+	if _, err := Load(RCDATA, "DATA"); err == nil || err.Error() != errUnsupportedSystem {
+		t.Error(err)
+	}
+	if _, err := LoadLang(RCDATA, "DATA", 0x409); err == nil || err.Error() != errUnsupportedSystem {
+		t.Error(err)
+	}
+}
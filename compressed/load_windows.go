@@ -0,0 +1,54 @@
+package compressed
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procFindResourceW  = kernel32.NewProc("FindResourceW")
+	procFindResourceEx = kernel32.NewProc("FindResourceExW")
+	procSizeofResource = kernel32.NewProc("SizeofResource")
+	procLoadResource   = kernel32.NewProc("LoadResource")
+	procLockResource   = kernel32.NewProc("LockResource")
+)
+
+// findResource returns the memory of a resource of the current executable.
+func findResource(typeName, resName string, langID *uint16) ([]byte, error) {
+	t, err := syscall.UTF16PtrFromString(typeName)
+	if err != nil {
+		return nil, err
+	}
+	n, err := syscall.UTF16PtrFromString(resName)
+	if err != nil {
+		return nil, err
+	}
+
+	var hrsrc uintptr
+	if langID != nil {
+		hrsrc, _, _ = procFindResourceEx.Call(0, uintptr(unsafe.Pointer(t)), uintptr(unsafe.Pointer(n)), uintptr(*langID))
+	} else {
+		hrsrc, _, _ = procFindResourceW.Call(0, uintptr(unsafe.Pointer(n)), uintptr(unsafe.Pointer(t)))
+	}
+	if hrsrc == 0 {
+		return nil, errors.New(errResourceNotFound)
+	}
+
+	size, _, _ := procSizeofResource.Call(0, hrsrc)
+	if size == 0 {
+		return []byte{}, nil
+	}
+	hglobal, _, err := procLoadResource.Call(0, hrsrc)
+	if hglobal == 0 {
+		return nil, err
+	}
+	ptr, _, err := procLockResource.Call(hglobal)
+	if ptr == 0 {
+		return nil, err
+	}
+
+	// Resources stay mapped as long as the module is loaded, which is forever for the executable.
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&ptr)), size), nil
+}
@@ -0,0 +1,463 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres/lcid"
+	"github.com/tc-hib/winres/version"
+)
+
+// ConfigFileName is the name of the JSON file read by LoadConfig and written by ExportConfig.
+const ConfigFileName = "winres.json"
+
+// The JSON configuration is organized by type, then resource, then language:
+//
+//	{
+//	  "RT_GROUP_ICON": {
+//	    "APP": {
+//	      "0000": "icon.png"
+//	    }
+//	  },
+//	  "RT_MANIFEST": {
+//	    "#1": {
+//	      "0409": {"execution-level": "administrator"}
+//	    }
+//	  }
+//	}
+//
+// Types are either standard type names (RT_ICON, RT_VERSION, ...), IDs prefixed with '#', or custom names.
+// Resources are either IDs prefixed with '#', or names.
+// Languages are LCIDs in hexadecimal, or BCP 47 tags such as "fr-FR".
+
+// LoadConfig makes a resource set from a winres.json file found in dir.
+//
+// File paths in the configuration are relative to dir.
+//
+// Values depend on the resource type:
+//
+//	RT_GROUP_ICON:   "icon.ico" or "icon.png", which will be resized to DefaultIconSizes
+//	                 ["icon16.png", "icon32.png"], where each png file is an image of the icon
+//	                 {"image": "icon.png", "sizes": [48, 32, 16]}
+//	RT_GROUP_CURSOR: "cursor.cur"
+//	                 {"image": "cursor.png", "hotspot": {"x": 1, "y": 2}}
+//	                 a list of the above
+//	RT_MANIFEST:     an object, as described by AppManifest's JSON tags
+//	                 "manifest.xml", which will be embedded as is
+//	RT_VERSION:      an object, as described by version.Info's JSON representation
+//	other types:     a path to a file, which will be embedded as is
+//
+// RT_VERSION is split into one resource per translation, as does SetVersionInfo,
+// so its language key is only meaningful when it has no string table.
+// When it describes a DLL, a manifest with ID 1 is moved to ID 2, as in SetVersionInfo.
+func LoadConfig(dir string) (*ResourceSet, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := map[string]json.RawMessage{}
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	rs := &ResourceSet{}
+	l := configLoader{dir: dir, rs: rs}
+
+	// Icon IDs depend on the order in which groups are added, so it must not be random.
+	for _, tk := range sortedConfigKeys(cfg) {
+		typeID, err := identFromConfig(tk, true)
+		if err != nil {
+			return nil, err
+		}
+		resources := map[string]json.RawMessage{}
+		if err = json.Unmarshal(cfg[tk], &resources); err != nil {
+			return nil, fmt.Errorf("%s: %w", tk, err)
+		}
+		for _, rk := range sortedConfigKeys(resources) {
+			resID, err := identFromConfig(rk, false)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", tk, rk, err)
+			}
+			langs := map[string]json.RawMessage{}
+			if err = json.Unmarshal(resources[rk], &langs); err != nil {
+				return nil, fmt.Errorf("%s/%s: %w", tk, rk, err)
+			}
+			for _, lk := range sortedConfigKeys(langs) {
+				langID, err := langIDFromConfig(lk)
+				if err != nil {
+					return nil, fmt.Errorf("%s/%s/%s: %w", tk, rk, lk, err)
+				}
+				if err = l.load(typeID, resID, langID, langs[lk]); err != nil {
+					return nil, fmt.Errorf("%s/%s/%s: %w", tk, rk, lk, err)
+				}
+			}
+		}
+	}
+	// RT_MANIFEST is loaded before RT_VERSION
+	rs.updateManifestID()
+
+	return rs, nil
+}
+
+// ExportConfig writes a winres.json file and the files it refers to into dir,
+// so that LoadConfig can rebuild the resource set.
+//
+// Icons are exported as ICO files, cursors as CUR files, manifests as xml files,
+// version info as JSON, and other resources as binary files.
+//
+// RT_ICON and RT_CURSOR resources are only exported through their groups.
+func (rs *ResourceSet) ExportConfig(dir string) error {
+	var (
+		cfg      = map[string]map[string]map[string]interface{}{}
+		versions = map[Identifier]map[uint16]*version.Info{}
+		used     = map[string]bool{}
+		err      error
+	)
+
+	set := func(typeID, resID Identifier, langID uint16, v interface{}) {
+		tk, rk, lk := identToConfig(typeID, true), identToConfig(resID, false), fmt.Sprintf("%04X", langID)
+		if cfg[tk] == nil {
+			cfg[tk] = map[string]map[string]interface{}{}
+		}
+		if cfg[tk][rk] == nil {
+			cfg[tk][rk] = map[string]interface{}{}
+		}
+		cfg[tk][rk][lk] = v
+	}
+
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		var (
+			ext = ".bin"
+			buf = &bytes.Buffer{}
+		)
+
+		switch typeID {
+		case RT_ICON, RT_CURSOR:
+			return true
+		case RT_VERSION:
+			vi, e := version.FromBytes(data)
+			if e == nil {
+				if versions[resID] == nil {
+					versions[resID] = map[uint16]*version.Info{}
+				}
+				versions[resID][langID] = vi
+				return true
+			}
+		case RT_MANIFEST:
+			ext = ".xml"
+		case RT_GROUP_ICON:
+			if icon, e := rs.GetIconTranslation(resID, langID); e == nil && icon.SaveICO(buf) == nil {
+				ext = ".ico"
+				data = buf.Bytes()
+			}
+		case RT_GROUP_CURSOR:
+			if cursor, e := rs.GetCursorTranslation(resID, langID); e == nil && cursor.SaveCUR(buf) == nil {
+				ext = ".cur"
+				data = buf.Bytes()
+			}
+		}
+
+		name := configFileName(typeID, resID, langID)
+		// File systems may be case insensitive, and cleaning names may produce duplicates
+		for i := 2; used[strings.ToLower(name+ext)]; i++ {
+			name = configFileName(typeID, resID, langID) + "-" + strconv.Itoa(i)
+		}
+		used[strings.ToLower(name+ext)] = true
+
+		err = os.WriteFile(filepath.Join(dir, name+ext), data, 0666)
+		if err != nil {
+			return false
+		}
+		set(typeID, resID, langID, name+ext)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for resID, translations := range versions {
+		set(RT_VERSION, resID, LCIDNeutral, version.MergeTranslations(translations))
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ConfigFileName), data, 0666)
+}
+
+type configLoader struct {
+	dir string
+	rs  *ResourceSet
+}
+
+type iconConfig struct {
+	Image string `json:"image"`
+	Sizes []int  `json:"sizes,omitempty"`
+}
+
+type cursorConfig struct {
+	Image   string `json:"image"`
+	HotSpot struct {
+		X uint16 `json:"x"`
+		Y uint16 `json:"y"`
+	} `json:"hotspot"`
+}
+
+func (l *configLoader) load(typeID, resID Identifier, langID uint16, value json.RawMessage) error {
+	switch typeID {
+	case RT_GROUP_ICON:
+		icon, err := l.loadIcon(value)
+		if err != nil {
+			return err
+		}
+		return l.rs.SetIconTranslation(resID, langID, icon)
+
+	case RT_GROUP_CURSOR:
+		cursor, err := l.loadCursor(value)
+		if err != nil {
+			return err
+		}
+		return l.rs.SetCursorTranslation(resID, langID, cursor)
+
+	case RT_MANIFEST:
+		var m AppManifest
+		if json.Unmarshal(value, &m) == nil {
+			return l.rs.Set(typeID, resID, langID, makeManifest(m))
+		}
+
+	case RT_VERSION:
+		var vi version.Info
+		if json.Unmarshal(value, &vi) == nil {
+			translations := vi.SplitTranslations()
+			if len(translations) == 0 {
+				return l.rs.Set(typeID, resID, langID, vi.Bytes())
+			}
+			for langID, tr := range translations {
+				if err := l.rs.Set(typeID, resID, langID, tr.Bytes()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	var filename string
+	if err := json.Unmarshal(value, &filename); err != nil {
+		return errors.New(errInvalidConfigValue)
+	}
+	data, err := os.ReadFile(filepath.Join(l.dir, filename))
+	if err != nil {
+		return err
+	}
+	return l.rs.Set(typeID, resID, langID, data)
+}
+
+func (l *configLoader) loadIcon(value json.RawMessage) (*Icon, error) {
+	var (
+		filename string
+		items    []json.RawMessage
+		cfg      iconConfig
+	)
+
+	if json.Unmarshal(value, &filename) == nil {
+		return l.loadIconImages(iconConfig{Image: filename}, true)
+	}
+	if json.Unmarshal(value, &cfg) == nil {
+		return l.loadIconImages(cfg, true)
+	}
+	if err := json.Unmarshal(value, &items); err != nil {
+		return nil, errors.New(errInvalidConfigValue)
+	}
+
+	icon := &Icon{}
+	for _, item := range items {
+		cfg = iconConfig{}
+		if json.Unmarshal(item, &cfg.Image) != nil && json.Unmarshal(item, &cfg) != nil {
+			return nil, errors.New(errInvalidConfigValue)
+		}
+		part, err := l.loadIconImages(cfg, false)
+		if err != nil {
+			return nil, err
+		}
+		icon.Images = append(icon.Images, part.Images...)
+	}
+	return icon, nil
+}
+
+// loadIconImages loads an ICO file, or an image file.
+//
+// An image file is resized to the sizes specified in cfg.
+// If no size was specified, it is resized to DefaultIconSizes only when resize is true.
+func (l *configLoader) loadIconImages(cfg iconConfig, resize bool) (*Icon, error) {
+	f, err := os.Open(filepath.Join(l.dir, cfg.Image))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(cfg.Image), ".ico") {
+		return LoadICO(f)
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Sizes == nil && !resize {
+		return NewIconFromImages([]image.Image{img})
+	}
+	return NewIconFromResizedImage(img, cfg.Sizes)
+}
+
+func (l *configLoader) loadCursor(value json.RawMessage) (*Cursor, error) {
+	var (
+		filename string
+		items    []json.RawMessage
+		cfg      cursorConfig
+	)
+
+	if json.Unmarshal(value, &filename) == nil {
+		f, err := os.Open(filepath.Join(l.dir, filename))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return LoadCUR(f)
+	}
+	if json.Unmarshal(value, &cfg) == nil {
+		items = []json.RawMessage{value}
+	} else if err := json.Unmarshal(value, &items); err != nil {
+		return nil, errors.New(errInvalidConfigValue)
+	}
+
+	var images []CursorImage
+	for _, item := range items {
+		cfg = cursorConfig{}
+		if err := json.Unmarshal(item, &cfg); err != nil {
+			return nil, errors.New(errInvalidConfigValue)
+		}
+		img, err := l.loadImage(cfg.Image)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, CursorImage{
+			Image:   img,
+			HotSpot: HotSpot{X: cfg.HotSpot.X, Y: cfg.HotSpot.Y},
+		})
+	}
+	return NewCursorFromImages(images)
+}
+
+func (l *configLoader) loadImage(filename string) (image.Image, error) {
+	f, err := os.Open(filepath.Join(l.dir, filename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+var typeNames = map[ID]string{
+	RT_CURSOR:       "RT_CURSOR",
+	RT_BITMAP:       "RT_BITMAP",
+	RT_ICON:         "RT_ICON",
+	RT_MENU:         "RT_MENU",
+	RT_DIALOG:       "RT_DIALOG",
+	RT_STRING:       "RT_STRING",
+	RT_FONTDIR:      "RT_FONTDIR",
+	RT_FONT:         "RT_FONT",
+	RT_ACCELERATOR:  "RT_ACCELERATOR",
+	RT_RCDATA:       "RT_RCDATA",
+	RT_MESSAGETABLE: "RT_MESSAGETABLE",
+	RT_GROUP_CURSOR: "RT_GROUP_CURSOR",
+	RT_GROUP_ICON:   "RT_GROUP_ICON",
+	RT_VERSION:      "RT_VERSION",
+	RT_PLUGPLAY:     "RT_PLUGPLAY",
+	RT_VXD:          "RT_VXD",
+	RT_ANICURSOR:    "RT_ANICURSOR",
+	RT_ANIICON:      "RT_ANIICON",
+	RT_HTML:         "RT_HTML",
+	RT_MANIFEST:     "RT_MANIFEST",
+}
+
+// identFromConfig parses an identifier written as "#42", "NAME", or "RT_ICON" when it is a type.
+func identFromConfig(s string, isType bool) (Identifier, error) {
+	if isType {
+		for id, name := range typeNames {
+			if s == name {
+				return id, nil
+			}
+		}
+	}
+
+	if strings.HasPrefix(s, "#") {
+		n, err := strconv.ParseUint(s[1:], 10, 16)
+		if err == nil {
+			return ID(n), checkIdentifier(ID(n))
+		}
+	}
+
+	return Name(s), checkIdentifier(Name(s))
+}
+
+func identToConfig(ident Identifier, isType bool) string {
+	switch ident := ident.(type) {
+	case ID:
+		if name, ok := typeNames[ident]; ok && isType {
+			return name
+		}
+		return "#" + strconv.Itoa(int(ident))
+	case Name:
+		return string(ident)
+	}
+	return ""
+}
+
+func langIDFromConfig(s string) (uint16, error) {
+	if id, err := lcid.Parse(s); err == nil {
+		return id, nil
+	}
+	n, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, errors.New(errInvalidLangID)
+	}
+	return uint16(n), nil
+}
+
+// configFileName returns a file name for an exported resource, without extension.
+func configFileName(typeID, resID Identifier, langID uint16) string {
+	return fmt.Sprintf("%s-%s-%04X", identToFileName(typeID, true), identToFileName(resID, false), langID)
+}
+
+func identToFileName(ident Identifier, isType bool) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
+			return r
+		}
+		if r == '#' {
+			return -1
+		}
+		return '_'
+	}, identToConfig(ident, isType))
+}
+
+func sortedConfigKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,247 @@
+package winres
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tc-hib/winres/version"
+)
+
+func writeTestFile(t *testing.T, dir, name string, data string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestPNG(t *testing.T, dir, name string, size int) {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = png.Encode(f, image.NewNRGBA(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestPNG(t, dir, "icon.png", 64)
+	writeTestPNG(t, dir, "icon16.png", 16)
+	writeTestPNG(t, dir, "cursor.png", 32)
+	f, _ := os.Create(filepath.Join(dir, "icon.ico"))
+	newTestIcon(t, 48, 24).SaveICO(f)
+	f.Close()
+	writeTestFile(t, dir, "manifest.xml", "<assembly/>")
+	writeTestFile(t, dir, "data.bin", "data")
+	// language=json
+	writeTestFile(t, dir, ConfigFileName, `{
+  "RT_GROUP_ICON": {
+    "APP": {"0000": "icon.png"},
+    "#2": {"0000": ["icon.png", "icon16.png"], "040C": "icon.ico"},
+    "#3": {"0000": {"image": "icon.png", "sizes": [32, 16]}}
+  },
+  "RT_GROUP_CURSOR": {
+    "#1": {"0000": {"image": "cursor.png", "hotspot": {"x": 3, "y": 4}}}
+  },
+  "RT_MANIFEST": {
+    "#1": {"0409": {"execution-level": "administrator"}},
+    "#2": {"0409": "manifest.xml"}
+  },
+  "RT_VERSION": {
+    "#1": {"0000": {"fixed": {"file_version": "1.2.3.4"}, "info": {"0409": {"ProductName": "Product"}, "fr-FR": {"ProductName": "Produit"}}}}
+  },
+  "RT_RCDATA": {"#42": {"0000": "data.bin"}},
+  "CUSTOM": {"NAME": {"en-US": "data.bin"}}
+}`)
+
+	rs, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkIconSizes := func(resID Identifier, langID uint16, sizes ...int) {
+		icon, err := rs.GetIconTranslation(resID, langID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, img := range icon.Images {
+			got = append(got, int(img.Info.Width-1)+1)
+		}
+		if !reflect.DeepEqual(got, sizes) {
+			t.Errorf("%v %04X: %v, want %v", resID, langID, got, sizes)
+		}
+	}
+	checkIconSizes(Name("APP"), 0, DefaultIconSizes...)
+	checkIconSizes(ID(2), 0, 64, 16)
+	checkIconSizes(ID(2), 0x40C, 48, 24)
+	checkIconSizes(ID(3), 0, 32, 16)
+
+	cursor, err := rs.GetCursor(ID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cursor.images) != 1 || cursor.images[0].hotSpot != (HotSpot{3, 4}) {
+		t.Fail()
+	}
+
+	m, err := AppManifestFromXML(rs.Get(RT_MANIFEST, ID(1), 0x409))
+	if err != nil || m.ExecutionLevel != RequireAdministrator {
+		t.Fail()
+	}
+	if string(rs.Get(RT_MANIFEST, ID(2), 0x409)) != "<assembly/>" {
+		t.Fail()
+	}
+
+	vi, err := version.FromBytes(rs.Get(RT_VERSION, ID(1), 0x40C))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.FileVersion != [4]uint16{1, 2, 3, 4} || vi.Get(0x40C, version.ProductName) != "Produit" {
+		t.Fail()
+	}
+	if rs.Get(RT_VERSION, ID(1), 0x409) == nil || rs.Get(RT_VERSION, ID(1), 0) != nil {
+		t.Fail()
+	}
+
+	if string(rs.Get(RT_RCDATA, ID(42), 0)) != "data" || string(rs.Get(Name("CUSTOM"), Name("NAME"), 0x409)) != "data" {
+		t.Fail()
+	}
+}
+
+func TestLoadConfig_DLLManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ConfigFileName, `{
+  "RT_MANIFEST": {"#1": {"0409": {}}},
+  "RT_VERSION": {"#1": {"0000": {"fixed": {"type": "DLL"}}}}
+}`)
+
+	rs, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Get(RT_MANIFEST, ISOLATIONAWARE_MANIFEST_RESOURCE_ID, 0x409) == nil || rs.Get(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID, 0x409) != nil {
+		t.Error("a DLL manifest should be moved to ID 2")
+	}
+}
+
+func TestLoadConfig_Deterministic(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, dir, "icon.png", 16)
+	writeTestFile(t, dir, ConfigFileName, `{"RT_GROUP_ICON": {"A": {"0000": "icon.png"}, "B": {"0000": "icon.png"}, "#1": {"0000": "icon.png"}}}`)
+
+	for i := 0; i < 10; i++ {
+		rs, err := LoadConfig(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rs.Get(RT_GROUP_ICON, ID(1), 0)[6+12] != 1 {
+			t.Fatal("groups should be loaded in a fixed order")
+		}
+	}
+}
+
+func TestLoadConfig_Err(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{name: "syntax", json: `{`, wantErr: "*"},
+		{name: "type", json: `{"": {}}`, wantErr: errEmptyName},
+		{name: "resources", json: `{"RT_RCDATA": []}`, wantErr: "*"},
+		{name: "resource", json: `{"RT_RCDATA": {"#0": {}}}`, wantErr: errZeroID},
+		{name: "langs", json: `{"RT_RCDATA": {"#1": 1}}`, wantErr: "*"},
+		{name: "lang", json: `{"RT_RCDATA": {"#1": {"en-XX": "x"}}}`, wantErr: errInvalidLangID},
+		{name: "value", json: `{"RT_RCDATA": {"#1": {"0000": 42}}}`, wantErr: errInvalidConfigValue},
+		{name: "file", json: `{"RT_RCDATA": {"#1": {"0000": "missing.bin"}}}`, wantErr: "*"},
+		{name: "icon", json: `{"RT_GROUP_ICON": {"#1": {"0000": 42}}}`, wantErr: errInvalidConfigValue},
+		{name: "iconItem", json: `{"RT_GROUP_ICON": {"#1": {"0000": [42]}}}`, wantErr: errInvalidConfigValue},
+		{name: "iconFile", json: `{"RT_GROUP_ICON": {"#1": {"0000": ["missing.png"]}}}`, wantErr: "*"},
+		{name: "iconImage", json: `{"RT_GROUP_ICON": {"#1": {"0000": "winres.json"}}}`, wantErr: "*"},
+		{name: "cursor", json: `{"RT_GROUP_CURSOR": {"#1": {"0000": 42}}}`, wantErr: errInvalidConfigValue},
+		{name: "cursorItem", json: `{"RT_GROUP_CURSOR": {"#1": {"0000": [42]}}}`, wantErr: errInvalidConfigValue},
+		{name: "cursorFile", json: `{"RT_GROUP_CURSOR": {"#1": {"0000": "missing.cur"}}}`, wantErr: "*"},
+		{name: "cursorImage", json: `{"RT_GROUP_CURSOR": {"#1": {"0000": {"image": "missing.png"}}}}`, wantErr: "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, ConfigFileName, tt.json)
+			rs, err := LoadConfig(dir)
+			if rs != nil || !isErr(err, tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(t.TempDir()); !os.IsNotExist(err) {
+		t.Fail()
+	}
+}
+
+func TestResourceSet_ExportConfig(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIcon(Name("APP"), newTestIcon(t, 32, 16))
+	rs.SetIconTranslation(Name("APP"), 0x40C, newTestIcon(t, 48))
+	rs.SetCursor(ID(1), newTestCursor(t, 32))
+	rs.SetManifest(AppManifest{DPIAwareness: DPIPerMonitorV2})
+	vi := version.Info{ProductVersion: [4]uint16{1, 2, 3, 4}}
+	vi.Set(0x409, version.ProductName, "Product")
+	vi.Set(0x40C, version.ProductName, "Produit")
+	rs.SetVersionInfo(vi)
+	rs.Set(RT_RCDATA, ID(1), 0, []byte("data"))
+	rs.Set(Name("my type"), Name("name"), 0, []byte("a"))
+	rs.Set(Name("my type"), Name("NAME"), 0, []byte("b"))
+	rs.Set(Name("my type"), Name("NAME?"), 0, []byte("c"))
+
+	dir := t.TempDir()
+	if err := rs.ExportConfig(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	want := []string{
+		"RT_GROUP_CURSOR-1-0000.cur",
+		"RT_GROUP_ICON-APP-0000.ico",
+		"RT_GROUP_ICON-APP-040C.ico",
+		"RT_MANIFEST-1-0409.xml",
+		"RT_RCDATA-1-0000.bin",
+		"my_type-NAME-0000.bin",
+		"my_type-NAME_-0000.bin",
+		"my_type-name-0000-2.bin",
+		"winres.json",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files: %v\nwant: %v", files, want)
+	}
+
+	loaded, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(rs, loaded); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestResourceSet_ExportConfig_Err(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.Set(RT_RCDATA, ID(1), 0, []byte("data"))
+
+	err := rs.ExportConfig(filepath.Join(t.TempDir(), "missing"))
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fail()
+	}
+}
@@ -172,6 +172,14 @@ func (rs *ResourceSet) SetCursorTranslation(resID Identifier, langID uint16, cur
 	return rs.Set(RT_GROUP_CURSOR, resID, langID, b.Bytes())
 }
 
+// DeleteCursor removes a cursor group from the resource set, in every language.
+//
+// Cursor images that were referenced by this group are removed too,
+// unless another group still references them.
+func (rs *ResourceSet) DeleteCursor(resID Identifier) error {
+	return rs.deleteGroup(RT_GROUP_CURSOR, RT_CURSOR, resID)
+}
+
 // GetCursor extracts a cursor from a resource set.
 func (rs *ResourceSet) GetCursor(resID Identifier) (*Cursor, error) {
 	return rs.GetCursorTranslation(resID, rs.firstLang(RT_GROUP_CURSOR, resID))
@@ -0,0 +1,324 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tc-hib/winres/version"
+)
+
+// ChangeKind tells how a resource changed between two resource sets.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change describes the difference between two resource sets, for a single resource translation.
+type Change struct {
+	Kind   ChangeKind
+	TypeID Identifier
+	ResID  Identifier
+	LangID uint16
+	// Details is a list of human readable differences, when the resource type is known.
+	// For example: `fixed.file_version: "1.0.0.0" -> "1.0.0.1"`
+	Details []string
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s/%s/%04X", c.Kind, identString(c.TypeID), identString(c.ResID), c.LangID)
+	if len(c.Details) > 0 {
+		s += ": " + strings.Join(c.Details, "; ")
+	}
+	return s
+}
+
+// Diff compares two resource sets and returns the list of resources that were added, removed, or modified
+// between a and b, in the same order as they would be written.
+//
+// Known types are compared semantically, so that details can explain what changed:
+//
+//	RT_VERSION:      fixed file info and string tables
+//	RT_MANIFEST:     settings, as read by AppManifestFromXML
+//	RT_GROUP_ICON:   image sizes and bit depths, and image data
+//	RT_GROUP_CURSOR: image sizes, bit depths and hot spots, and image data
+//
+// RT_ICON and RT_CURSOR resources are compared through their groups, because their IDs are arbitrary.
+// So they are not reported.
+//
+// A nil resource set is considered empty.
+func Diff(a, b *ResourceSet) []Change {
+	var (
+		changes []Change
+		union   = &ResourceSet{}
+	)
+
+	if a == nil {
+		a = &ResourceSet{}
+	}
+	if b == nil {
+		b = &ResourceSet{}
+	}
+
+	for _, rs := range []*ResourceSet{a, b} {
+		rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+			if typeID != RT_ICON && typeID != RT_CURSOR {
+				union.set(typeID, resID, langID, []byte{})
+			}
+			return true
+		})
+	}
+
+	union.Walk(func(typeID, resID Identifier, langID uint16, _ []byte) bool {
+		c := Change{
+			TypeID: typeID,
+			ResID:  resID,
+			LangID: langID,
+		}
+		dataA, dataB := a.Get(typeID, resID, langID), b.Get(typeID, resID, langID)
+		switch {
+		case dataA == nil:
+			c.Kind = Added
+		case dataB == nil:
+			c.Kind = Removed
+		default:
+			c.Kind = Modified
+			c.Details = diffResource(a, b, typeID, resID, langID)
+			if c.Details == nil {
+				return true
+			}
+		}
+		changes = append(changes, c)
+		return true
+	})
+
+	return changes
+}
+
+// diffResource returns the differences between two translations of a same resource, or nil if they are equivalent.
+func diffResource(a, b *ResourceSet, typeID, resID Identifier, langID uint16) []string {
+	dataA, dataB := a.Get(typeID, resID, langID), b.Get(typeID, resID, langID)
+
+	switch typeID {
+	case RT_GROUP_ICON:
+		if d, ok := diffIcons(a, b, resID, langID); ok {
+			return d
+		}
+	case RT_GROUP_CURSOR:
+		if d, ok := diffCursors(a, b, resID, langID); ok {
+			return d
+		}
+	}
+
+	if bytes.Equal(dataA, dataB) {
+		return nil
+	}
+
+	switch typeID {
+	case RT_VERSION:
+		if d, ok := diffVersionInfo(dataA, dataB); ok {
+			return d
+		}
+	case RT_MANIFEST:
+		if d, ok := diffManifests(dataA, dataB); ok {
+			return d
+		}
+	}
+
+	if len(dataA) != len(dataB) {
+		return []string{fmt.Sprintf("size: %d -> %d bytes", len(dataA), len(dataB))}
+	}
+	return []string{"data changed"}
+}
+
+func diffVersionInfo(dataA, dataB []byte) ([]string, bool) {
+	viA, err := version.FromBytes(dataA)
+	if err != nil {
+		return nil, false
+	}
+	viB, err := version.FromBytes(dataB)
+	if err != nil {
+		return nil, false
+	}
+	return diffAsJSON(viA, viB)
+}
+
+func diffManifests(dataA, dataB []byte) ([]string, bool) {
+	mA, err := AppManifestFromXML(dataA)
+	if err != nil {
+		return nil, false
+	}
+	mB, err := AppManifestFromXML(dataB)
+	if err != nil {
+		return nil, false
+	}
+	d, ok := diffAsJSON(mA, mB)
+	if ok && d == nil {
+		// Settings are equivalent but the xml is different
+		d = []string{"xml changed"}
+	}
+	return d, ok
+}
+
+func diffIcons(a, b *ResourceSet, resID Identifier, langID uint16) ([]string, bool) {
+	iconA, err := a.GetIconTranslation(resID, langID)
+	if err != nil {
+		return nil, false
+	}
+	iconB, err := b.GetIconTranslation(resID, langID)
+	if err != nil {
+		return nil, false
+	}
+
+	var descA, descB, dataA, dataB []string
+	for _, img := range iconA.Images {
+		descA = append(descA, iconImageString(img.Info))
+		dataA = append(dataA, string(img.Image))
+	}
+	for _, img := range iconB.Images {
+		descB = append(descB, iconImageString(img.Info))
+		dataB = append(dataB, string(img.Image))
+	}
+
+	return diffImages(descA, descB, dataA, dataB), true
+}
+
+func diffCursors(a, b *ResourceSet, resID Identifier, langID uint16) ([]string, bool) {
+	curA, err := a.GetCursorTranslation(resID, langID)
+	if err != nil {
+		return nil, false
+	}
+	curB, err := b.GetCursorTranslation(resID, langID)
+	if err != nil {
+		return nil, false
+	}
+
+	var descA, descB, dataA, dataB []string
+	for _, img := range curA.images {
+		descA = append(descA, cursorImageString(img))
+		dataA = append(dataA, string(img.image))
+	}
+	for _, img := range curB.images {
+		descB = append(descB, cursorImageString(img))
+		dataB = append(dataB, string(img.image))
+	}
+
+	return diffImages(descA, descB, dataA, dataB), true
+}
+
+// diffImages compares two lists of images, first by their description, then by their data.
+func diffImages(descA, descB, dataA, dataB []string) []string {
+	if strings.Join(descA, ", ") != strings.Join(descB, ", ") {
+		return []string{fmt.Sprintf("images: [%s] -> [%s]", strings.Join(descA, ", "), strings.Join(descB, ", "))}
+	}
+
+	var d []string
+	for i := range dataA {
+		if dataA[i] != dataB[i] {
+			d = append(d, fmt.Sprintf("image %d (%s): data changed", i, descA[i]))
+		}
+	}
+	return d
+}
+
+func iconImageString(info IconInfo) string {
+	return fmt.Sprintf("%dx%d %dbpp", int(info.Width-1)+1, int(info.Height-1)+1, info.BitCount)
+}
+
+func cursorImageString(img cursorImage) string {
+	return fmt.Sprintf("%dx%d %dbpp hotspot %d,%d", img.info.Width, img.info.Height, img.info.BitCount, img.hotSpot.X, img.hotSpot.Y)
+}
+
+// diffAsJSON compares the JSON representations of two values.
+//
+// It returns false if one of them cannot be marshalled.
+func diffAsJSON(a, b interface{}) ([]string, bool) {
+	var va, vb interface{}
+	if !toJSONValue(a, &va) || !toJSONValue(b, &vb) {
+		return nil, false
+	}
+	return diffJSONValues("", va, vb), true
+}
+
+func toJSONValue(v interface{}, out *interface{}) bool {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(j, out) == nil
+}
+
+func diffJSONValues(path string, a, b interface{}) []string {
+	mapA, okA := a.(map[string]interface{})
+	mapB, okB := b.(map[string]interface{})
+	// A missing object is compared as an empty one, to get details
+	if okA && b == nil {
+		okB = true
+	}
+	if okB && a == nil {
+		okA = true
+	}
+	if !okA || !okB {
+		ja, _ := json.Marshal(a)
+		jb, _ := json.Marshal(b)
+		if bytes.Equal(ja, jb) {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: %s -> %s", path, jsonOrNone(a, ja), jsonOrNone(b, jb))}
+	}
+
+	keys := make([]string, 0, len(mapA)+len(mapB))
+	for k := range mapA {
+		keys = append(keys, k)
+	}
+	for k := range mapB {
+		if _, ok := mapA[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var d []string
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		d = append(d, diffJSONValues(p, mapA[k], mapB[k])...)
+	}
+	return d
+}
+
+func jsonOrNone(v interface{}, j []byte) string {
+	if v == nil {
+		return "(none)"
+	}
+	return string(j)
+}
+
+func identString(ident Identifier) string {
+	switch ident := ident.(type) {
+	case ID:
+		return fmt.Sprint(int(ident))
+	case Name:
+		return fmt.Sprintf("%q", string(ident))
+	}
+	return ""
+}
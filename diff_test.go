@@ -0,0 +1,134 @@
+package winres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres/version"
+)
+
+func TestDiff(t *testing.T) {
+	a, b := &ResourceSet{}, &ResourceSet{}
+
+	vi := version.Info{FileVersion: [4]uint16{1, 0, 0, 0}}
+	vi.Set(0x409, version.ProductName, "Product")
+	vi.Set(0x409, version.CompanyName, "Company")
+	a.SetVersionInfo(vi)
+	vi.FileVersion[3] = 1
+	vi.Flags.Prerelease = true
+	vi.Set(0x409, version.ProductName, "New Product")
+	vi.Set(0x409, version.Comments, "Comments")
+	b.SetVersionInfo(vi)
+
+	a.SetManifest(AppManifest{})
+	b.SetManifest(AppManifest{ExecutionLevel: RequireAdministrator, LongPathAware: true})
+
+	a.SetIcon(ID(1), newTestIcon(t, 32, 16))
+	b.SetIcon(ID(1), newTestIcon(t, 48, 16))
+	a.SetIcon(ID(2), newTestIcon(t, 16))
+	b.SetIcon(ID(2), newTestIcon(t, 16))
+
+	a.SetCursor(ID(1), newTestCursor(t, 32))
+	b.Set(RT_RCDATA, ID(1), 0, []byte{1})
+	b.SetCursor(ID(1), newTestCursor(t, 32))
+
+	a.Set(RT_RCDATA, Name("SAME"), 0, []byte{1, 2})
+	b.Set(RT_RCDATA, Name("SAME"), 0, []byte{1, 2})
+	a.Set(RT_RCDATA, Name("DATA"), 0, []byte{1, 2})
+	b.Set(RT_RCDATA, Name("DATA"), 0, []byte{1, 2, 3})
+	a.Set(RT_RCDATA, Name("DATA"), 0x40C, []byte{1, 2})
+	b.Set(RT_RCDATA, Name("DATA"), 0x40C, []byte{1, 3})
+	a.Set(RT_RCDATA, Name("OLD"), 0, []byte{1})
+
+	want := []Change{
+		{Kind: Modified, TypeID: RT_RCDATA, ResID: Name("DATA"), LangID: 0, Details: []string{"size: 2 -> 3 bytes"}},
+		{Kind: Modified, TypeID: RT_RCDATA, ResID: Name("DATA"), LangID: 0x40C, Details: []string{"data changed"}},
+		{Kind: Removed, TypeID: RT_RCDATA, ResID: Name("OLD"), LangID: 0},
+		{Kind: Added, TypeID: RT_RCDATA, ResID: ID(1), LangID: 0},
+		{Kind: Modified, TypeID: RT_GROUP_ICON, ResID: ID(1), LangID: 0, Details: []string{"images: [32x32 32bpp, 16x16 32bpp] -> [48x48 32bpp, 16x16 32bpp]"}},
+		{Kind: Modified, TypeID: RT_VERSION, ResID: ID(1), LangID: 0x409, Details: []string{
+			`fixed.file_version: "1.0.0.0" -> "1.0.0.1"`,
+			`fixed.flags: (none) -> "Prerelease"`,
+			`info.0409.Comments: (none) -> "Comments"`,
+			`info.0409.ProductName: "Product" -> "New Product"`,
+		}},
+		{Kind: Modified, TypeID: RT_MANIFEST, ResID: ID(1), LangID: LCIDDefault, Details: []string{
+			`execution-level: "" -> "administrator"`,
+			`long-path-aware: false -> true`,
+		}},
+	}
+
+	got := Diff(a, b)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff():\n%v\nwant:\n%v", got, want)
+	}
+
+	if Diff(b, b) != nil {
+		t.Fail()
+	}
+}
+
+func TestDiff_Images(t *testing.T) {
+	a, b := &ResourceSet{}, &ResourceSet{}
+
+	a.SetIcon(ID(1), newTestIcon(t, 16))
+	a.Compact(true)
+	b.SetIcon(ID(1), newTestIcon(t, 32))
+	b.SetIcon(ID(1), newTestIcon(t, 16))
+	if Diff(a, b) != nil {
+		t.Error("IDs of images should not matter")
+	}
+
+	img := append([]byte{}, b.Get(RT_ICON, ID(2), 0)...)
+	img[len(img)-1]++
+	b.Set(RT_ICON, ID(2), 0, img)
+	b.SetCursor(ID(1), newTestCursor(t, 16))
+
+	want := []Change{
+		{Kind: Added, TypeID: RT_GROUP_CURSOR, ResID: ID(1), LangID: 0},
+		{Kind: Modified, TypeID: RT_GROUP_ICON, ResID: ID(1), LangID: 0, Details: []string{"image 0 (16x16 32bpp): data changed"}},
+	}
+	got := Diff(a, b)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff():\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestDiff_Nil(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.Set(Name("TYPE"), Name("NAME"), 0x409, []byte{})
+
+	got := Diff(nil, rs)
+	if len(got) != 1 || got[0].String() != `added "TYPE"/"NAME"/0409` {
+		t.Error(got)
+	}
+	got = Diff(rs, nil)
+	if len(got) != 1 || got[0].String() != `removed "TYPE"/"NAME"/0409` {
+		t.Error(got)
+	}
+}
+
+func TestChange_String(t *testing.T) {
+	c := Change{Kind: Modified, TypeID: RT_VERSION, ResID: ID(1), LangID: 0x409, Details: []string{"a", "b"}}
+	if c.String() != "modified 16/1/0409: a; b" {
+		t.Error(c.String())
+	}
+	if ChangeKind(42).String() != "unknown" {
+		t.Fail()
+	}
+}
+
+func Test_diffJSONValues(t *testing.T) {
+	var a, b interface{}
+	toJSONValue(map[string]interface{}{"x": 1}, &a)
+	toJSONValue(map[string]interface{}{"x": 1, "y": map[string]string{"z": "z"}}, &b)
+
+	got := diffJSONValues("", a, b)
+	if !reflect.DeepEqual(got, []string{`y.z: (none) -> "z"`}) {
+		t.Error(got)
+	}
+	got = diffJSONValues("", b, a)
+	if !reflect.DeepEqual(got, []string{`y.z: "z" -> (none)`}) {
+		t.Error(got)
+	}
+}
@@ -0,0 +1,211 @@
+package winres
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"io"
+)
+
+// DLLOptions are the options of WriteDLL.
+//
+// The zero value is a sensible default.
+type DLLOptions struct {
+	// ImageBase is the preferred load address of the DLL.
+	// Defaults to 0x10000000 for 32-bit architectures and 0x180000000 for 64-bit ones.
+	ImageBase uint64
+	// TimeDateStamp is written in the COFF header.
+	// It is zero by default, to keep the output reproducible.
+	TimeDateStamp uint32
+	// ImageVersion is the major and minor version of the image, as in the optional header.
+	ImageVersion [2]uint16
+}
+
+const (
+	dllSectionAlignment = 0x1000
+	dllFileAlignment    = 0x200
+	dllStubLength       = 0x80
+)
+
+// dllStub is the classic MS-DOS header and stub, pointing to a PE header at 0x80.
+var dllStub = [dllStubLength]byte{
+	'M', 'Z', 0x90, 0x00, 0x03, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0x00, 0x00,
+	0xB8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, dllStubLength, 0x00, 0x00, 0x00,
+	0x0E, 0x1F, 0xBA, 0x0E, 0x00, 0xB4, 0x09, 0xCD, 0x21, 0xB8, 0x01, 0x4C, 0xCD, 0x21, 'T', 'h',
+	'i', 's', ' ', 'p', 'r', 'o', 'g', 'r', 'a', 'm', ' ', 'c', 'a', 'n', 'n', 'o',
+	't', ' ', 'b', 'e', ' ', 'r', 'u', 'n', ' ', 'i', 'n', ' ', 'D', 'O', 'S', ' ',
+	'm', 'o', 'd', 'e', '.', '\r', '\r', '\n', '$', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// dllWriter holds the headers of a resource-only DLL and the content of its .rsrc section.
+type dllWriter struct {
+	h    peHeaders
+	rsrc *rsrcContent
+}
+
+func writeDLL(w io.Writer, rs *ResourceSet, arch Arch, opts DLLOptions) error {
+	machine, err := machineType(arch)
+	if err != nil {
+		return err
+	}
+
+	dw := dllWriter{rsrc: rs.directory()}
+	dw.prepareHeaders(machine, opts)
+
+	addRVA(dw.rsrc.dir, dw.rsrc.reloc, dw.h.sections[0].VirtualAddress)
+
+	c := peCheckSum{}
+	if err = dw.write(&c); err != nil {
+		return err
+	}
+	dw.h.opt.setCheckSum(c.Sum())
+
+	return dw.write(w)
+}
+
+func (dw *dllWriter) prepareHeaders(machine uint16, opts DLLOptions) {
+	const (
+		osMajor = 6
+		osMinor = 0
+		// Windows on ARM requires Windows 8 as a minimum.
+		armOSMinor = 2
+	)
+
+	dw.h.file = pe.FileHeader{
+		Machine:          machine,
+		NumberOfSections: 1,
+		TimeDateStamp:    opts.TimeDateStamp,
+		Characteristics:  pe.IMAGE_FILE_EXECUTABLE_IMAGE | pe.IMAGE_FILE_DLL,
+	}
+	dw.h.dirs = make([]pe.DataDirectory, 16)
+
+	rsrcLen := dw.rsrc.size
+	virtSize := roundUp(rsrcLen, dllSectionAlignment)
+	if virtSize == 0 {
+		virtSize = dllSectionAlignment
+	}
+
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_I386, pe.IMAGE_FILE_MACHINE_ARMNT:
+		dw.h.file.Characteristics |= pe.IMAGE_FILE_32BIT_MACHINE
+		opt := &peOptionalHeader32{
+			Magic:                       0x10B,
+			ImageBase:                   uint32(opts.ImageBase),
+			SectionAlignment:            dllSectionAlignment,
+			FileAlignment:               dllFileAlignment,
+			MajorOperatingSystemVersion: osMajor,
+			MinorOperatingSystemVersion: osMinor,
+			MajorImageVersion:           opts.ImageVersion[0],
+			MinorImageVersion:           opts.ImageVersion[1],
+			MajorSubsystemVersion:       osMajor,
+			MinorSubsystemVersion:       osMinor,
+			Subsystem:                   pe.IMAGE_SUBSYSTEM_WINDOWS_GUI,
+			DllCharacteristics:          pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE | pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT,
+			SizeOfStackReserve:          0x100000,
+			SizeOfStackCommit:           0x1000,
+			SizeOfHeapReserve:           0x100000,
+			SizeOfHeapCommit:            0x1000,
+			NumberOfRvaAndSizes:         uint32(len(dw.h.dirs)),
+		}
+		if opt.ImageBase == 0 {
+			opt.ImageBase = 0x10000000
+		}
+		if machine == pe.IMAGE_FILE_MACHINE_ARMNT {
+			opt.MinorOperatingSystemVersion = armOSMinor
+			opt.MinorSubsystemVersion = armOSMinor
+		}
+		dw.h.opt = opt
+	default:
+		dw.h.file.Characteristics |= pe.IMAGE_FILE_LARGE_ADDRESS_AWARE
+		opt := &peOptionalHeader64{
+			Magic:                       0x20B,
+			ImageBase:                   opts.ImageBase,
+			SectionAlignment:            dllSectionAlignment,
+			FileAlignment:               dllFileAlignment,
+			MajorOperatingSystemVersion: osMajor,
+			MinorOperatingSystemVersion: osMinor,
+			MajorImageVersion:           opts.ImageVersion[0],
+			MinorImageVersion:           opts.ImageVersion[1],
+			MajorSubsystemVersion:       osMajor,
+			MinorSubsystemVersion:       osMinor,
+			Subsystem:                   pe.IMAGE_SUBSYSTEM_WINDOWS_GUI,
+			DllCharacteristics: pe.IMAGE_DLLCHARACTERISTICS_HIGH_ENTROPY_VA |
+				pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE | pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT,
+			SizeOfStackReserve:  0x100000,
+			SizeOfStackCommit:   0x1000,
+			SizeOfHeapReserve:   0x100000,
+			SizeOfHeapCommit:    0x1000,
+			NumberOfRvaAndSizes: uint32(len(dw.h.dirs)),
+		}
+		if opt.ImageBase == 0 {
+			opt.ImageBase = 0x180000000
+		}
+		if machine == pe.IMAGE_FILE_MACHINE_ARM64 {
+			opt.MinorOperatingSystemVersion = armOSMinor
+			opt.MinorSubsystemVersion = armOSMinor
+		}
+		dw.h.opt = opt
+	}
+
+	dw.h.file.SizeOfOptionalHeader = uint16(binary.Size(dw.h.opt) + len(dw.h.dirs)*8)
+	dw.h.stubLength = dllStubLength
+	dw.h.length = dllStubLength + 4 + int64(binary.Size(dw.h.file)) + int64(dw.h.file.SizeOfOptionalHeader) + sizeOfSectionHeader
+	sizeOfHeaders := roundUp(uint32(dw.h.length), dllFileAlignment)
+
+	dw.h.sections = []pe.SectionHeader32{{
+		Name:             [8]uint8{'.', 'r', 's', 'r', 'c'},
+		VirtualSize:      rsrcLen,
+		VirtualAddress:   dllSectionAlignment,
+		SizeOfRawData:    roundUp(rsrcLen, dllFileAlignment),
+		PointerToRawData: sizeOfHeaders,
+		Characteristics:  _IMAGE_SCN_MEM_READ | _IMAGE_SCN_CNT_INITIALIZED_DATA,
+	}}
+	dw.h.dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE] = pe.DataDirectory{
+		VirtualAddress: dllSectionAlignment,
+		Size:           rsrcLen,
+	}
+
+	switch opt := dw.h.opt.(type) {
+	case *peOptionalHeader32:
+		opt.SizeOfHeaders = sizeOfHeaders
+	case *peOptionalHeader64:
+		opt.SizeOfHeaders = sizeOfHeaders
+	}
+	dw.h.opt.setSizeOfInitializedData(dw.h.sections[0].SizeOfRawData)
+	dw.h.opt.setSizeOfImage(dllSectionAlignment + virtSize)
+}
+
+func (dw *dllWriter) write(w io.Writer) error {
+	if _, err := w.Write(dllStub[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{'P', 'E', 0, 0}); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, &dw.h.file); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, dw.h.opt); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, dw.h.dirs); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, dw.h.sections); err != nil {
+		return err
+	}
+	sec := &dw.h.sections[0]
+	if err := writeBlank(w, int64(sec.PointerToRawData)-dw.h.length); err != nil {
+		return err
+	}
+	if err := dw.rsrc.writeTo(w); err != nil {
+		return err
+	}
+	return writeBlank(w, int64(sec.SizeOfRawData)-int64(dw.rsrc.size))
+}
+
+func roundUp(p uint32, a uint32) uint32 {
+	x := p + a - 1
+	return x - x%a
+}
@@ -0,0 +1,127 @@
+package winres
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"testing"
+)
+
+func TestResourceSet_WriteDLL(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIcon(Name("APP"), newTestIcon(t, 32, 16))
+	rs.Set(RT_RCDATA, ID(1), 0x40C, []byte("données"))
+	rs.Set(Name("CUSTOM"), Name("NAME"), 0, []byte("custom"))
+
+	tests := []struct {
+		arch      Arch
+		machine   uint16
+		imageBase uint64
+	}{
+		{ArchI386, pe.IMAGE_FILE_MACHINE_I386, 0x10000000},
+		{ArchAMD64, pe.IMAGE_FILE_MACHINE_AMD64, 0x180000000},
+		{ArchARM, pe.IMAGE_FILE_MACHINE_ARMNT, 0x10000000},
+		{ArchARM64, pe.IMAGE_FILE_MACHINE_ARM64, 0x180000000},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.arch), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := rs.WriteDLL(buf, tt.arch, DLLOptions{ImageVersion: [2]uint16{1, 2}}); err != nil {
+				t.Fatal(err)
+			}
+			data := buf.Bytes()
+			if len(data)%dllFileAlignment != 0 {
+				t.Error("file size should be aligned")
+			}
+
+			f, err := pe.NewFile(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Machine != tt.machine || f.Characteristics&pe.IMAGE_FILE_DLL == 0 || len(f.Sections) != 1 {
+				t.Errorf("%+v", f.FileHeader)
+			}
+			var imageBase uint64
+			var checkSum uint32
+			var entryPoint uint32
+			var imageVersion [2]uint16
+			switch opt := f.OptionalHeader.(type) {
+			case *pe.OptionalHeader32:
+				imageBase, checkSum, entryPoint = uint64(opt.ImageBase), opt.CheckSum, opt.AddressOfEntryPoint
+				imageVersion = [2]uint16{opt.MajorImageVersion, opt.MinorImageVersion}
+			case *pe.OptionalHeader64:
+				imageBase, checkSum, entryPoint = opt.ImageBase, opt.CheckSum, opt.AddressOfEntryPoint
+				imageVersion = [2]uint16{opt.MajorImageVersion, opt.MinorImageVersion}
+			}
+			if imageBase != tt.imageBase || entryPoint != 0 || imageVersion != [2]uint16{1, 2} {
+				t.Errorf("%+v", f.OptionalHeader)
+			}
+
+			// The checksum is computed with the CheckSum field set to zero
+			h, _ := readPEHeaders(bytes.NewReader(data))
+			h.opt.setCheckSum(0)
+			c := peCheckSum{}
+			c.Write(data[:h.stubLength+4])
+			binary.Write(&c, binary.LittleEndian, &h.file)
+			binary.Write(&c, binary.LittleEndian, h.opt)
+			c.Write(data[h.stubLength+4+int64(binary.Size(h.file))+int64(binary.Size(h.opt)):])
+			if checkSum == 0 || c.Sum() != checkSum {
+				t.Errorf("checksum: %08X, expected %08X", checkSum, c.Sum())
+			}
+
+			loaded, err := LoadFromEXE(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := Diff(rs, loaded); d != nil {
+				t.Error(d)
+			}
+
+			// The DLL can be patched
+			out := &bytes.Buffer{}
+			rs2 := &ResourceSet{}
+			rs2.Set(RT_RCDATA, ID(2), 0, bytes.Repeat([]byte{1}, 0x1800))
+			if err = rs2.WriteToEXE(out, bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err = LoadFromEXE(bytes.NewReader(out.Bytes()))
+			if err != nil || loaded.Count() != 1 || len(loaded.Get(RT_RCDATA, ID(2), 0)) != 0x1800 {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestResourceSet_WriteDLL_Empty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := (&ResourceSet{}).WriteDLL(buf, ArchAMD64, DLLOptions{ImageBase: 0x400000, TimeDateStamp: 42}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := pe.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := f.OptionalHeader.(*pe.OptionalHeader64)
+	if f.TimeDateStamp != 42 || opt.ImageBase != 0x400000 || opt.SizeOfImage != 0x2000 {
+		t.Errorf("%+v %+v", f.FileHeader, opt)
+	}
+	rs, err := LoadFromEXE(bytes.NewReader(buf.Bytes()))
+	if err != nil || rs.Count() != 0 {
+		t.Error(err)
+	}
+}
+
+func TestResourceSet_WriteDLL_Err(t *testing.T) {
+	if err := (&ResourceSet{}).WriteDLL(&bytes.Buffer{}, "ppc", DLLOptions{}); !isErr(err, errUnknownArch) {
+		t.Error(err)
+	}
+	rs := &ResourceSet{}
+	rs.Set(RT_RCDATA, ID(1), 0, []byte("data"))
+	buf := &bytes.Buffer{}
+	rs.WriteDLL(buf, ArchAMD64, DLLOptions{})
+	for _, n := range []int{1, 0x81, 0x85, 0x99, 0x189, 0x1B1, 0x1D9, 0x201, 0x3F0, buf.Len()} {
+		if err := rs.WriteDLL(newBadWriter(n), ArchAMD64, DLLOptions{}); !isExpectedWriteErr(err) {
+			t.Errorf("%X: %v", n, err)
+		}
+	}
+}
@@ -23,6 +23,10 @@ const (
 
 	errInvalidResDir        = "invalid resource directory"
 	errDataEntryOutOfBounds = "data entry out of bounds"
+	errDataSizeTooBig       = "resource data too big"
+	errNegativeDataSize     = "resource data size is negative"
+	errDataSizeMismatch     = "resource data size doesn't match the declared size"
+	errCannotCompressType   = "only RT_RCDATA and custom types may be compressed"
 
 	errNotPEImage    = "not a valid PE image"
 	errSignedPE      = "cannot modify a signed PE image"
@@ -38,6 +42,22 @@ const (
 	errUnknownSupportedOS  = "unknown minimum-os value"
 	errUnknownDPIAwareness = "unknown dpi-awareness value"
 	errUnknownExecLevel    = "unknown execution-level value"
+
+	errInvalidManifest       = "invalid manifest, root element must be <assembly>"
+	errUnknownWindowsSetting = "unknown windows setting"
+	errNoManifest            = "manifest not found"
+	errNoVersionInfo         = "version info not found"
+
+	errNoMUIConfig        = "MUI configuration not found"
+	errInvalidMUIConfig   = "invalid MUI configuration"
+	errMUIFallbackMissing = "fallback language has no localizable resources"
+
+	errInvalidConfigValue = "invalid value in configuration"
+	errInvalidLangID      = "invalid language id"
+
+	errIdentifierCaseCollision = "identifiers differ only by case"
+	errInvalidResourcePath     = "invalid resource path"
+	errNotBMP                  = "not a valid BMP file"
 )
 
 // ErrNoResources is the error returned by LoadFromEXE when it didn't find a .rsrc section.
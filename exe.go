@@ -197,17 +197,35 @@ func (h *peOptionalHeader64) setCheckSum(c uint32) {
 }
 
 func extractRSRCSection(r io.ReadSeeker) ([]byte, uint32, error) {
+	sec, err := findRSRCSection(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data := make([]byte, sec.SizeOfRawData)
+
+	r.Seek(int64(sec.PointerToRawData), io.SeekStart)
+	err = readFull(r, data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, sec.VirtualAddress, nil
+}
+
+// findRSRCSection returns the header of the section holding the resource directory.
+func findRSRCSection(r io.ReadSeeker) (*pe.SectionHeader32, error) {
 	r.Seek(0, io.SeekStart)
 
 	fileSize := getSeekerSize(r)
 
 	h, err := readPEHeaders(r)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	if h.dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].VirtualAddress == 0 && h.dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].Size == 0 {
-		return nil, 0, ErrNoResources
+		return nil, ErrNoResources
 	}
 
 	var sec *pe.SectionHeader32
@@ -218,26 +236,36 @@ func extractRSRCSection(r io.ReadSeeker) ([]byte, uint32, error) {
 		}
 	}
 	if sec == nil {
-		return nil, 0, errors.New(errRSRCNotFound)
+		return nil, errors.New(errRSRCNotFound)
 	}
 
 	if int64(sec.PointerToRawData)+int64(sec.SizeOfRawData) > fileSize {
-		return nil, 0, errors.New(errSectionTooFar)
+		return nil, errors.New(errSectionTooFar)
 	}
-	data := make([]byte, sec.SizeOfRawData)
 
-	r.Seek(int64(sec.PointerToRawData), io.SeekStart)
-	err = readFull(r, data)
-	if err != nil {
-		return nil, 0, err
-	}
+	return sec, nil
+}
 
-	return data, sec.VirtualAddress, nil
+// rsrcContent is the content of a .rsrc section, whose resource data may be streamed from its source.
+type rsrcContent struct {
+	dir   []byte                // directory, data entries and names
+	reloc []int                 // offsets of the data entries' addresses in dir
+	size  uint32                // size of the whole content, including resource data
+	write func(io.Writer) error // writes resource data, which follows dir
+}
+
+// writeTo writes the whole content.
+func (c *rsrcContent) writeTo(w io.Writer) error {
+	if _, err := w.Write(c.dir); err != nil {
+		return err
+	}
+	return c.write(w)
 }
 
 type peWriter struct {
 	h        *peHeaders
-	rsrcData []byte
+	rsrc     *rsrcContent
+	rsrcLen  uint32 // size of the .rsrc section in headers, which may be greater than rsrc.size
 	rsrcHdr  *pe.SectionHeader32
 	relocHdr *pe.SectionHeader32
 	src      struct {
@@ -251,33 +279,36 @@ type peWriter struct {
 	}
 }
 
-func replaceRSRCSection(dst io.Writer, src io.ReadSeeker, rsrcData []byte, reloc []int, options exeOptions) error {
+func replaceRSRCSection(dst io.Writer, src io.ReadSeeker, rsrc *rsrcContent, options exeOptions) error {
 	src.Seek(0, io.SeekStart)
 
-	pew, err := preparePEWriter(src, rsrcData, options.authenticodeHandling)
+	pew, err := preparePEWriter(src, rsrc, options.authenticodeHandling)
 	if err != nil {
 		return err
 	}
 
-	pew.applyReloc(reloc)
+	pew.applyReloc()
 
 	if options.forceCheckSum || pew.h.hasChecksum {
 		c := peCheckSum{}
-		pew.writeEXE(&c)
+		if err = pew.writeEXE(&c); err != nil {
+			return err
+		}
 		pew.h.opt.setCheckSum(c.Sum())
 	}
 
 	return pew.writeEXE(dst)
 }
 
-func preparePEWriter(src io.ReadSeeker, rsrcData []byte, sigHandling authenticodeHandling) (*peWriter, error) {
+func preparePEWriter(src io.ReadSeeker, rsrc *rsrcContent, sigHandling authenticodeHandling) (*peWriter, error) {
 	var (
 		pew peWriter
 		err error
 	)
 
 	pew.src.r = src
-	pew.rsrcData = rsrcData
+	pew.rsrc = rsrc
+	pew.rsrcLen = rsrc.size
 
 	pew.src.fileSize = getSeekerSize(src)
 
@@ -380,11 +411,9 @@ func (pew *peWriter) requiresNewSection() bool {
 	}
 
 	// From here, we should not shift data after the existing .rsrc section
-	if pew.rsrcHdr.SizeOfRawData >= uint32(len(pew.rsrcData)) {
+	if pew.rsrcHdr.SizeOfRawData >= pew.rsrcLen {
 		// The .rsrc section won't grow, so we only have to ensure it won't shrink too much either
-		buf := make([]byte, pew.rsrcHdr.SizeOfRawData)
-		copy(buf, pew.rsrcData)
-		pew.rsrcData = buf
+		pew.rsrcLen = pew.rsrcHdr.SizeOfRawData
 		return false
 	}
 
@@ -393,7 +422,7 @@ func (pew *peWriter) requiresNewSection() bool {
 
 func (pew *peWriter) updateHeaders() {
 	var (
-		rsrcLen     = uint32(len(pew.rsrcData))
+		rsrcLen     = pew.rsrcLen
 		lastSection *pe.SectionHeader32
 		oldSize     uint32
 		virtDelta   uint32
@@ -405,7 +434,7 @@ func (pew *peWriter) updateHeaders() {
 			Name:             [8]uint8{'.', 'r', 's', 'r', 'c'},
 			VirtualSize:      rsrcLen,
 			VirtualAddress:   pew.roundVirt(pew.src.virtEnd),
-			SizeOfRawData:    pew.roundRaw(uint32(len(pew.rsrcData))),
+			SizeOfRawData:    pew.roundRaw(rsrcLen),
 			PointerToRawData: pew.roundRaw(pew.src.dataEnd),
 			Characteristics:  _IMAGE_SCN_MEM_READ | _IMAGE_SCN_CNT_INITIALIZED_DATA,
 		})
@@ -463,17 +492,22 @@ func (pew *peWriter) roundVirt(p uint32) uint32 {
 	return x - x%a
 }
 
-func (pew *peWriter) applyReloc(reloc []int) {
+func (pew *peWriter) applyReloc() {
+	addRVA(pew.rsrc.dir, pew.rsrc.reloc, pew.rsrcHdr.VirtualAddress)
+}
+
+// addRVA adds the virtual address of the .rsrc section to the data entries found at offsets reloc.
+func addRVA(rsrcData []byte, reloc []int, rva uint32) {
 	for _, o := range reloc {
-		addr := uint32(pew.rsrcData[o+3])<<24 |
-			uint32(pew.rsrcData[o+2])<<16 |
-			uint32(pew.rsrcData[o+1])<<8 |
-			uint32(pew.rsrcData[o])
-		addr += pew.rsrcHdr.VirtualAddress
-		pew.rsrcData[o+3] = uint8(addr >> 24)
-		pew.rsrcData[o+2] = uint8(addr >> 16)
-		pew.rsrcData[o+1] = uint8(addr >> 8)
-		pew.rsrcData[o] = uint8(addr)
+		addr := uint32(rsrcData[o+3])<<24 |
+			uint32(rsrcData[o+2])<<16 |
+			uint32(rsrcData[o+1])<<8 |
+			uint32(rsrcData[o])
+		addr += rva
+		rsrcData[o+3] = uint8(addr >> 24)
+		rsrcData[o+2] = uint8(addr >> 16)
+		rsrcData[o+1] = uint8(addr >> 8)
+		rsrcData[o] = uint8(addr)
 	}
 }
 
@@ -532,11 +566,11 @@ func (pew *peWriter) writeEXE(w io.Writer) error {
 	}
 
 	// .rsrc
-	_, err = w.Write(pew.rsrcData)
+	err = pew.rsrc.writeTo(w)
 	if err != nil {
 		return err
 	}
-	err = writeBlank(w, int64(pew.rsrcHdr.SizeOfRawData)-int64(len(pew.rsrcData)))
+	err = writeBlank(w, int64(pew.rsrcHdr.SizeOfRawData)-int64(pew.rsrc.size))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,385 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tc-hib/winres/version"
+)
+
+// ExtractOptions are the options of ExtractTo.
+type ExtractOptions struct {
+	// Raw disables conversions: every resource is written as is, with a .bin extension,
+	// including the RT_ICON and RT_CURSOR images that are otherwise part of .ico and .cur files.
+	Raw bool
+}
+
+// ExtractTo writes every resource of the set into a directory tree, one file per translation:
+//
+//	dir/<type>/<resource>/<language>.<ext>
+//
+// Types and resources are named as in winres.json, e.g. "RT_GROUP_ICON", "#42" or "NAME".
+// Characters that are not safe in file names are percent-encoded.
+// Languages are written as 4 hexadecimal digits, e.g. "0409".
+//
+// Unless opts.Raw is true, known types are converted into usable formats:
+//
+//	RT_GROUP_ICON:   .ico
+//	RT_GROUP_CURSOR: .cur
+//	RT_MANIFEST:     .xml
+//	RT_VERSION:      .json
+//	RT_BITMAP:       .bmp, with its file header
+//	RT_HTML:         the extension found in the resource name, or .html
+//
+// Other resources are written with a .bin extension.
+//
+// ImportFrom reads the tree back.
+func (rs *ResourceSet) ExtractTo(dir string, opts ExtractOptions) error {
+	var (
+		used = map[string]string{}
+		err  error
+	)
+
+	// File systems may be case insensitive
+	mkdir := func(path string) error {
+		key := strings.ToLower(path)
+		if p, ok := used[key]; ok {
+			if p != path {
+				return fmt.Errorf("%s: %w", path, errors.New(errIdentifierCaseCollision))
+			}
+			return nil
+		}
+		used[key] = path
+		return os.MkdirAll(filepath.Join(dir, path), 0777)
+	}
+
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		ext := ".bin"
+		if !opts.Raw {
+			if typeID == RT_ICON || typeID == RT_CURSOR {
+				return true
+			}
+			data, ext = rs.extractData(typeID, resID, langID, data)
+		}
+
+		path := identToPath(typeID, true)
+		if err = mkdir(path); err != nil {
+			return false
+		}
+		path = filepath.Join(path, identToPath(resID, false))
+		if err = mkdir(path); err != nil {
+			return false
+		}
+		err = os.WriteFile(filepath.Join(dir, path, fmt.Sprintf("%04X", langID)+ext), data, 0666)
+		return err == nil
+	})
+
+	return err
+}
+
+// extractData converts a resource into the format ExtractTo writes, and returns its file extension.
+func (rs *ResourceSet) extractData(typeID, resID Identifier, langID uint16, data []byte) ([]byte, string) {
+	buf := &bytes.Buffer{}
+
+	switch typeID {
+	case RT_GROUP_ICON:
+		if icon, err := rs.GetIconTranslation(resID, langID); err == nil && icon.SaveICO(buf) == nil {
+			return buf.Bytes(), ".ico"
+		}
+	case RT_GROUP_CURSOR:
+		if cursor, err := rs.GetCursorTranslation(resID, langID); err == nil && cursor.SaveCUR(buf) == nil {
+			return buf.Bytes(), ".cur"
+		}
+	case RT_MANIFEST:
+		return data, ".xml"
+	case RT_VERSION:
+		if vi, err := version.FromBytes(data); err == nil {
+			if j, err := json.MarshalIndent(vi, "", "  "); err == nil {
+				return j, ".json"
+			}
+		}
+	case RT_BITMAP:
+		if offset, ok := bitmapBitsOffset(data); ok {
+			hdr := [sizeOfBitmapFileHeader]byte{'B', 'M'}
+			binary.LittleEndian.PutUint32(hdr[2:], uint32(sizeOfBitmapFileHeader+len(data)))
+			binary.LittleEndian.PutUint32(hdr[10:], uint32(sizeOfBitmapFileHeader+offset))
+			return append(hdr[:], data...), ".bmp"
+		}
+	case RT_HTML:
+		ext := ".html"
+		if name, ok := resID.(Name); ok {
+			if e := filepath.Ext(string(name)); len(e) > 1 && strings.Trim(e[1:], "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz") == "" {
+				ext = strings.ToLower(e)
+			}
+		}
+		return data, ext
+	}
+
+	return data, ".bin"
+}
+
+// ImportFrom adds to the set all the resources found in a directory tree written by ExtractTo.
+//
+// Files are converted back according to their type and extension, so that .ico, .cur, .json and .bmp files
+// may be edited or replaced. Any other file is loaded as is.
+//
+// Hidden files and directories, whose name starts with a dot, are ignored.
+func (rs *ResourceSet) ImportFrom(dir string) error {
+	type typeDir struct {
+		ident Identifier
+		name  string
+	}
+
+	entries, err := readDirs(dir)
+	if err != nil {
+		return err
+	}
+	types := make([]typeDir, 0, len(entries))
+	for _, name := range entries {
+		typeID, err := identFromPath(name, true)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Join(dir, name), err)
+		}
+		types = append(types, typeDir{typeID, name})
+	}
+	// Raw images must be there before groups are converted, so that new images get new IDs.
+	sort.SliceStable(types, func(i, j int) bool {
+		isImage := func(ident Identifier) bool { return ident == RT_ICON || ident == RT_CURSOR }
+		return isImage(types[i].ident) && !isImage(types[j].ident)
+	})
+
+	for _, t := range types {
+		resources, err := readDirs(filepath.Join(dir, t.name))
+		if err != nil {
+			return err
+		}
+		for _, name := range resources {
+			path := filepath.Join(dir, t.name, name)
+			resID, err := identFromPath(name, false)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if err = rs.importResource(t.ident, resID, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rs *ResourceSet) importResource(typeID, resID Identifier, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		ext := filepath.Ext(e.Name())
+		langID, err := langIDFromConfig(strings.TrimSuffix(e.Name(), ext))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err = rs.importFile(typeID, resID, langID, path, strings.ToLower(ext)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func (rs *ResourceSet) importFile(typeID, resID Identifier, langID uint16, path string, ext string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case typeID == RT_GROUP_ICON && ext == ".ico":
+		icon, err := LoadICO(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		return rs.SetIconTranslation(resID, langID, icon)
+
+	case typeID == RT_GROUP_CURSOR && ext == ".cur":
+		cursor, err := LoadCUR(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		return rs.SetCursorTranslation(resID, langID, cursor)
+
+	case typeID == RT_VERSION && ext == ".json":
+		var vi version.Info
+		if err := json.Unmarshal(data, &vi); err != nil {
+			return err
+		}
+		data = vi.Bytes()
+
+	case typeID == RT_BITMAP && ext == ".bmp":
+		if len(data) < sizeOfBitmapFileHeader || data[0] != 'B' || data[1] != 'M' {
+			return errors.New(errNotBMP)
+		}
+		data = data[sizeOfBitmapFileHeader:]
+	}
+
+	return rs.Set(typeID, resID, langID, data)
+}
+
+// readDirs returns the sorted names of the sub-directories of dir, ignoring hidden ones.
+func readDirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+const sizeOfBitmapFileHeader = 14
+
+// bitmapBitsOffset returns the offset of the pixels in a DIB, which is a BMP file without its file header.
+func bitmapBitsOffset(dib []byte) (int, bool) {
+	const (
+		sizeOfCoreHeader = 12
+		sizeOfInfoHeader = 40
+		biBitFields      = 3
+		biAlphaBitFields = 6
+	)
+
+	if len(dib) < sizeOfCoreHeader {
+		return 0, false
+	}
+	hdrSize := int(binary.LittleEndian.Uint32(dib))
+	if hdrSize == sizeOfCoreHeader {
+		bitCount := int(binary.LittleEndian.Uint16(dib[10:]))
+		offset := hdrSize
+		if bitCount <= 8 {
+			offset += 3 << bitCount
+		}
+		return checkBitsOffset(offset, dib)
+	}
+	if hdrSize < sizeOfInfoHeader || len(dib) < sizeOfInfoHeader {
+		return 0, false
+	}
+
+	bitCount := int(binary.LittleEndian.Uint16(dib[14:]))
+	compression := binary.LittleEndian.Uint32(dib[16:])
+	colors := int(binary.LittleEndian.Uint32(dib[32:]))
+	if colors == 0 && bitCount <= 8 {
+		colors = 1 << bitCount
+	}
+	if colors > 256 {
+		return 0, false
+	}
+	offset := hdrSize + colors*4
+	if hdrSize == sizeOfInfoHeader {
+		// Color masks follow a BITMAPINFOHEADER, but are part of newer headers
+		switch compression {
+		case biBitFields:
+			offset += 12
+		case biAlphaBitFields:
+			offset += 16
+		}
+	}
+	return checkBitsOffset(offset, dib)
+}
+
+func checkBitsOffset(offset int, dib []byte) (int, bool) {
+	if offset > len(dib) {
+		return 0, false
+	}
+	return offset, true
+}
+
+// identToPath returns a file name for an identifier that identFromPath can read back.
+func identToPath(ident Identifier, isType bool) string {
+	name, ok := ident.(Name)
+	if !ok {
+		return identToConfig(ident, isType)
+	}
+
+	s := string(name)
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '-':
+			b.WriteByte(c)
+		case (c == '.' || c == ' ') && i > 0 && i < len(s)-1:
+			// Windows ignores leading spaces, and trailing dots and spaces
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	path := b.String()
+
+	// Avoid type names and reserved device names
+	base := strings.ToUpper(strings.SplitN(path, ".", 2)[0])
+	if ident, _ := identFromConfig(path, true); isType && ident != Name(path) || isReservedFileName(base) {
+		return fmt.Sprintf("%%%02X", path[0]) + path[1:]
+	}
+	return path
+}
+
+// identFromPath parses a file name returned by identToPath.
+func identFromPath(s string, isType bool) (Identifier, error) {
+	ident, err := identFromConfig(s, isType)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ident.(ID); ok {
+		return ident, nil
+	}
+	if strings.HasPrefix(s, "#") {
+		return nil, errors.New(errInvalidResourcePath)
+	}
+
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return nil, errors.New(errInvalidResourcePath)
+		}
+		c, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return nil, errors.New(errInvalidResourcePath)
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+
+	return Name(b.String()), checkIdentifier(Name(b.String()))
+}
+
+func isReservedFileName(s string) bool {
+	switch s {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(s) == 4 && (strings.HasPrefix(s, "COM") || strings.HasPrefix(s, "LPT")) {
+		return '1' <= s[3] && s[3] <= '9'
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+
+	"github.com/tc-hib/winres/version"
+)
+
+func listFiles(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func newTestBitmap(t *testing.T) []byte {
+	img := image.NewPaletted(image.Rect(0, 0, 3, 2), color.Palette{color.Black, color.White})
+	img.Pix[1] = 1
+	buf := &bytes.Buffer{}
+	if err := bmp.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResourceSet_ExtractTo(t *testing.T) {
+	bmpFile := newTestBitmap(t)
+
+	rs := &ResourceSet{}
+	rs.SetIcon(Name("APP"), newTestIcon(t, 32, 16))
+	rs.SetIconTranslation(Name("APP"), 0x40C, newTestIcon(t, 48))
+	rs.SetCursor(ID(1), newTestCursor(t, 32))
+	rs.SetManifest(AppManifest{DPIAwareness: DPIPerMonitorV2})
+	vi := version.Info{ProductVersion: [4]uint16{1, 2, 3, 4}}
+	vi.Set(0x409, version.ProductName, "Product")
+	rs.SetVersionInfo(vi)
+	rs.Set(RT_BITMAP, ID(1), 0x409, bmpFile[14:])
+	rs.Set(RT_BITMAP, ID(2), 0, []byte("not a bitmap"))
+	rs.Set(RT_HTML, Name("INDEX.HTML"), 0, []byte("<html/>"))
+	rs.Set(RT_HTML, Name("STYLE.CSS"), 0, []byte("body {}"))
+	rs.Set(RT_HTML, ID(3), 0, []byte("<p/>"))
+	rs.Set(RT_RCDATA, ID(1), 0, []byte("data"))
+	rs.Set(Name("RT_ICON"), Name("a/b:c"), 0, []byte("a"))
+	rs.Set(Name("my type"), Name("#42"), 0, []byte("b"))
+	rs.Set(Name("my type"), Name("con"), 0, []byte("c"))
+	rs.Set(Name("my type"), Name(".é."), 0, []byte("d"))
+
+	dir := t.TempDir()
+	if err := rs.ExtractTo(dir, ExtractOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"%52T_ICON/a%2Fb%3Ac/0000.bin",
+		"RT_BITMAP/#1/0409.bmp",
+		"RT_BITMAP/#2/0000.bin",
+		"RT_GROUP_CURSOR/#1/0000.cur",
+		"RT_GROUP_ICON/APP/0000.ico",
+		"RT_GROUP_ICON/APP/040C.ico",
+		"RT_HTML/#3/0000.html",
+		"RT_HTML/INDEX.HTML/0000.html",
+		"RT_HTML/STYLE.CSS/0000.css",
+		"RT_MANIFEST/#1/0409.xml",
+		"RT_RCDATA/#1/0000.bin",
+		"RT_VERSION/#1/0409.json",
+		"my type/%2342/0000.bin",
+		"my type/%2E%C3%A9%2E/0000.bin",
+		"my type/%63on/0000.bin",
+	}
+	if files := listFiles(t, dir); !reflect.DeepEqual(files, want) {
+		t.Errorf("files: %v\nwant: %v", files, want)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "RT_BITMAP", "#1", "0409.bmp"))
+	if !bytes.Equal(data, bmpFile) {
+		t.Error("bitmap file should be restored")
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "RT_VERSION", "#1", "0409.json"))
+	if !bytes.Contains(data, []byte(`"ProductName": "Product"`)) {
+		t.Error(string(data))
+	}
+
+	loaded := &ResourceSet{}
+	if err := loaded.ImportFrom(dir); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(rs, loaded); d != nil {
+		t.Error(d)
+	}
+}
+
+func TestResourceSet_ExtractTo_Raw(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIcon(ID(1), newTestIcon(t, 16))
+	rs.Set(RT_BITMAP, ID(1), 0, newTestBitmap(t)[14:])
+	rs.Set(RT_RCDATA, ID(1), 0, []byte("data"))
+
+	dir := t.TempDir()
+	if err := rs.ExtractTo(dir, ExtractOptions{Raw: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"RT_BITMAP/#1/0000.bin",
+		"RT_GROUP_ICON/#1/0000.bin",
+		"RT_ICON/#1/0000.bin",
+		"RT_RCDATA/#1/0000.bin",
+	}
+	if files := listFiles(t, dir); !reflect.DeepEqual(files, want) {
+		t.Errorf("files: %v\nwant: %v", files, want)
+	}
+
+	// A converted icon may be added to a raw tree
+	f, _ := os.Create(filepath.Join(dir, "RT_GROUP_ICON", "#1", "040C.ico"))
+	newTestIcon(t, 32).SaveICO(f)
+	f.Close()
+	// Hidden files are ignored
+	os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0666)
+	os.Mkdir(filepath.Join(dir, ".git"), 0777)
+	os.WriteFile(filepath.Join(dir, "RT_RCDATA", "#1", ".hidden"), nil, 0666)
+
+	loaded := &ResourceSet{}
+	if err := loaded.ImportFrom(dir); err != nil {
+		t.Fatal(err)
+	}
+	for typeID, te := range rs.Types {
+		for resID := range te.Resources {
+			if !bytes.Equal(loaded.Get(typeID, resID, 0), rs.Get(typeID, resID, 0)) {
+				t.Errorf("%v/%v", typeID, resID)
+			}
+		}
+	}
+	icon, err := loaded.GetIconTranslation(ID(1), 0x40C)
+	if err != nil || len(icon.Images) != 1 || loaded.Get(RT_ICON, ID(2), 0) == nil {
+		t.Error(err)
+	}
+}
+
+func TestResourceSet_ExtractTo_Err(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.Set(RT_RCDATA, Name("NAME"), 0, []byte("a"))
+	rs.Set(RT_RCDATA, Name("name"), 0, []byte("b"))
+	if err := rs.ExtractTo(t.TempDir(), ExtractOptions{}); !isErr(err, errIdentifierCaseCollision) {
+		t.Error(err)
+	}
+
+	rs = &ResourceSet{}
+	rs.Set(RT_RCDATA, ID(1), 0, []byte("a"))
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0666)
+	if err := rs.ExtractTo(file, ExtractOptions{}); err == nil {
+		t.Fail()
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "RT_RCDATA", "#1", "0000.bin"), 0777)
+	if err := rs.ExtractTo(dir, ExtractOptions{}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestResourceSet_ImportFrom_Err(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		data    string
+		wantErr string
+	}{
+		{name: "type", path: "#0/#1/0000.bin", wantErr: errZeroID},
+		{name: "resource", path: "RT_RCDATA/#x/0000.bin", wantErr: errInvalidResourcePath},
+		{name: "escape", path: "RT_RCDATA/A%2/0000.bin", wantErr: errInvalidResourcePath},
+		{name: "hex", path: "RT_RCDATA/A%ZZ/0000.bin", wantErr: errInvalidResourcePath},
+		{name: "nul", path: "RT_RCDATA/A%00/0000.bin", wantErr: errNameContainsNUL},
+		{name: "lang", path: "RT_RCDATA/#1/x.bin", wantErr: errInvalidLangID},
+		{name: "ico", path: "RT_GROUP_ICON/#1/0000.ico", data: "x", wantErr: "*"},
+		{name: "cur", path: "RT_GROUP_CURSOR/#1/0000.cur", data: "x", wantErr: "*"},
+		{name: "json", path: "RT_VERSION/#1/0000.json", data: "x", wantErr: "*"},
+		{name: "bmp", path: "RT_BITMAP/#1/0000.bmp", data: "x", wantErr: errNotBMP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, filepath.FromSlash(tt.path))
+			os.MkdirAll(filepath.Dir(path), 0777)
+			os.WriteFile(path, []byte(tt.data), 0666)
+			rs := &ResourceSet{}
+			if err := rs.ImportFrom(dir); !isErr(err, tt.wantErr) || !strings.Contains(err.Error(), dir) {
+				t.Errorf("ImportFrom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (&ResourceSet{}).ImportFrom(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Error(err)
+	}
+}
+
+func Test_bitmapBitsOffset(t *testing.T) {
+	header := func(size uint32, bitCount uint16, compression uint32, colors uint32) []byte {
+		b := make([]byte, 200)
+		binary.LittleEndian.PutUint32(b, size)
+		if size == 12 {
+			binary.LittleEndian.PutUint16(b[10:], bitCount)
+			return b
+		}
+		binary.LittleEndian.PutUint16(b[14:], bitCount)
+		binary.LittleEndian.PutUint32(b[16:], compression)
+		binary.LittleEndian.PutUint32(b[32:], colors)
+		return b
+	}
+
+	tests := []struct {
+		name   string
+		dib    []byte
+		offset int
+		ok     bool
+	}{
+		{name: "short", dib: []byte{12, 0, 0, 0}},
+		{name: "core", dib: header(12, 4, 0, 0), offset: 12 + 16*3, ok: true},
+		{name: "core24", dib: header(12, 24, 0, 0), offset: 12, ok: true},
+		{name: "bad", dib: header(20, 24, 0, 0)},
+		{name: "info1", dib: header(40, 1, 0, 0), offset: 40 + 2*4, ok: true},
+		{name: "infoUsed", dib: header(40, 8, 0, 3), offset: 40 + 3*4, ok: true},
+		{name: "tooMany", dib: header(40, 8, 0, 257)},
+		{name: "bitfields", dib: header(40, 16, 3, 0), offset: 40 + 12, ok: true},
+		{name: "alphaBitfields", dib: header(40, 32, 6, 0), offset: 40 + 16, ok: true},
+		{name: "v5", dib: header(124, 32, 3, 0), offset: 124, ok: true},
+		{name: "eof", dib: header(124, 8, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, ok := bitmapBitsOffset(tt.dib)
+			if offset != tt.offset || ok != tt.ok {
+				t.Errorf("bitmapBitsOffset() = %d, %v, want %d, %v", offset, ok, tt.offset, tt.ok)
+			}
+		})
+	}
+}
@@ -155,6 +155,8 @@ func (rs *ResourceSet) SetIcon(resID Identifier, icon *Icon) error {
 //  1. First name in case-sensitive ascending order, or else...
 //  2. First ID in ascending order
 //
+// Replacing an existing icon leaves its images in the set, call Compact to remove them.
+//
 func (rs *ResourceSet) SetIconTranslation(resID Identifier, langID uint16, icon *Icon) error {
 	b := &bytes.Buffer{}
 	binary.Write(b, binary.LittleEndian, iconDirHeader{
@@ -179,6 +181,14 @@ func (rs *ResourceSet) SetIconTranslation(resID Identifier, langID uint16, icon
 	return rs.Set(RT_GROUP_ICON, resID, langID, b.Bytes())
 }
 
+// DeleteIcon removes an icon group from the resource set, in every language.
+//
+// Icon images that were referenced by this group are removed too,
+// unless another group still references them.
+func (rs *ResourceSet) DeleteIcon(resID Identifier) error {
+	return rs.deleteGroup(RT_GROUP_ICON, RT_ICON, resID)
+}
+
 // GetIcon extracts an icon from a resource set.
 func (rs *ResourceSet) GetIcon(resID Identifier) (*Icon, error) {
 	return rs.GetIconTranslation(resID, rs.firstLang(RT_GROUP_ICON, resID))
@@ -0,0 +1,5 @@
+package lcid
+
+const (
+	errUnknownTag = "unknown language tag"
+)
@@ -0,0 +1,122 @@
+// Package lcid maps Windows language identifiers (LCID) to BCP 47 language tags, such as "fr-FR", and back.
+//
+// Resources and VERSIONINFO translations are identified by LCIDs such as 0x040C,
+// which this package helps to write as more readable tags.
+package lcid
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// LangNeutral is the primary language ID for language agnostic data.
+	LangNeutral = 0x00
+	// SubLangNeutral is the sublanguage ID for language agnostic data.
+	SubLangNeutral = 0x00
+	// SubLangDefault is the sublanguage ID for the default sublanguage of a language, such as en-US for English.
+	SubLangDefault = 0x01
+	// SubLangSysDefault is the sublanguage ID for the system default sublanguage.
+	SubLangSysDefault = 0x02
+)
+
+// MakeLangID makes a language ID from a primary language ID and a sublanguage ID, as does MAKELANGID.
+func MakeLangID(primary, sub uint16) uint16 {
+	return sub<<10 | primary
+}
+
+// PrimaryLangID returns the primary language ID of a language ID, as does PRIMARYLANGID.
+func PrimaryLangID(langID uint16) uint16 {
+	return langID & 0x3FF
+}
+
+// SubLangID returns the sublanguage ID of a language ID, as does SUBLANGID.
+func SubLangID(langID uint16) uint16 {
+	return langID >> 10
+}
+
+var tagByID, idByTag = makeIndex()
+
+func makeIndex() (map[uint16]string, map[string]uint16) {
+	byID := make(map[uint16]string, len(lcids))
+	byTag := make(map[string]uint16, len(lcids))
+	for _, l := range lcids {
+		byID[l.id] = l.tag
+		byTag[normalize(l.tag)] = l.id
+	}
+	return byID, byTag
+}
+
+// Tag returns the BCP 47 tag of a language ID, such as "fr-FR" for 0x040C.
+//
+// It returns an empty string for the neutral language, or if the language ID is unknown.
+func Tag(langID uint16) string {
+	return tagByID[langID]
+}
+
+// Name returns the English name of a language ID, such as "French (France)" for 0x040C.
+//
+// It returns an empty string if the language ID is unknown.
+func Name(langID uint16) string {
+	if langID == LangNeutral {
+		return "Neutral"
+	}
+	tag := tagByID[langID]
+	if tag == "" {
+		return ""
+	}
+
+	var details []string
+	parts := strings.Split(tag, "-")
+	for _, p := range parts[1:] {
+		region, sort, _ := strings.Cut(p, "_")
+		if name, ok := scriptNames[region]; ok {
+			details = append(details, name)
+		} else if name, ok := regionNames[region]; ok {
+			details = append(details, name)
+		}
+		if sort == "tradnl" {
+			details = append(details, "Traditional Sort")
+		}
+	}
+	name := languageNames[parts[0]]
+	if len(details) > 0 {
+		name += " (" + strings.Join(details, ", ") + ")"
+	}
+	return name
+}
+
+// Parse returns the language ID of a BCP 47 tag, such as 0x040C for "fr-FR".
+//
+// It is case insensitive, and also accepts underscores, as in "fr_FR".
+// A tag with a script that Windows doesn't need, such as "zh-Hans-CN", is also accepted.
+func Parse(tag string) (uint16, error) {
+	key := normalize(tag)
+	if id, ok := idByTag[key]; ok {
+		return id, nil
+	}
+	// Remove the script
+	if parts := strings.Split(key, "-"); len(parts) == 3 && len(parts[1]) == 4 {
+		if id, ok := idByTag[parts[0]+"-"+parts[2]]; ok {
+			return id, nil
+		}
+	}
+	return 0, errors.New(errUnknownTag)
+}
+
+// MustParse is like Parse but panics if the tag is unknown.
+//
+// It is meant to be used with constant tags, as in:
+//
+//	rs.Set(winres.RT_RCDATA, winres.ID(1), lcid.MustParse("fr-FR"), data)
+func MustParse(tag string) uint16 {
+	id, err := Parse(tag)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+}
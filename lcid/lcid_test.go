@@ -0,0 +1,100 @@
+package lcid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	for _, l := range lcids {
+		if id, err := Parse(l.tag); err != nil || id != l.id {
+			t.Errorf("%s: expected %04X, got %04X (%v)", l.tag, l.id, id, err)
+		}
+		if Tag(l.id) != l.tag {
+			t.Errorf("%04X: expected %s, got %s", l.id, l.tag, Tag(l.id))
+		}
+		name := Name(l.id)
+		n := strings.Count(l.tag, "-")
+		if strings.HasSuffix(l.tag, "_tradnl") {
+			n++
+		}
+		if languageNames[strings.Split(l.tag, "-")[0]] == "" || n > 0 && strings.Count(name, ",")+1 != n {
+			t.Errorf("%04X: incomplete name %q", l.id, name)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want uint16
+		err  string
+	}{
+		{"en-US", 0x0409, ""},
+		{"fr-fr", 0x040C, ""},
+		{"FR_CA", 0x0C0C, ""},
+		{"fr", 0x000C, ""},
+		{"es-ES", 0x0C0A, ""},
+		{"es-ES_tradnl", 0x040A, ""},
+		{"sr-Latn-RS", 0x241A, ""},
+		{"zh-Hans-CN", 0x0804, ""},
+		{"zh-Hant", 0x7C04, ""},
+		{"en-419", 0, errUnknownTag},
+		{"fr-XX", 0, errUnknownTag},
+		{"", 0, errUnknownTag},
+		{"0409", 0, errUnknownTag},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.tag)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: expected error %q, got %v", tt.tag, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %04X, got %04X (%v)", tt.tag, tt.want, got, err)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	for id, name := range map[uint16]string{
+		0x0000: "Neutral",
+		0x0409: "English (United States)",
+		0x000C: "French",
+		0x040A: "Spanish (Spain, Traditional Sort)",
+		0x241A: "Serbian (Latin, Serbia)",
+		0x7C04: "Chinese (Traditional)",
+		0x2409: "English (Caribbean)",
+		0x0C00: "",
+	} {
+		if got := Name(id); got != name {
+			t.Errorf("%04X: expected %q, got %q", id, name, got)
+		}
+	}
+}
+
+func TestMakeLangID(t *testing.T) {
+	if MakeLangID(0x0C, SubLangDefault) != 0x040C || MakeLangID(LangNeutral, SubLangNeutral) != 0 {
+		t.Fail()
+	}
+	if PrimaryLangID(0x0C0C) != 0x0C || SubLangID(0x0C0C) != 0x03 {
+		t.Fail()
+	}
+	if PrimaryLangID(0xFFFF) != 0x3FF || SubLangID(0xFFFF) != 0x3F {
+		t.Fail()
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	if MustParse("de-DE") != 0x0407 {
+		t.Fail()
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fail()
+		}
+	}()
+	MustParse("xx")
+}
@@ -15,22 +15,31 @@ import (
 //
 // Its zero value corresponds to the most common case.
 type AppManifest struct {
-	Identity                          AssemblyIdentity `json:"identity"`
-	Description                       string           `json:"description"`
-	Compatibility                     SupportedOS      `json:"minimum-os"`
-	ExecutionLevel                    ExecutionLevel   `json:"execution-level"`
-	UIAccess                          bool             `json:"ui-access"` // Require access to other applications' UI elements
-	AutoElevate                       bool             `json:"auto-elevate"`
-	DPIAwareness                      DPIAwareness     `json:"dpi-awareness"`
-	DisableTheming                    bool             `json:"disable-theming"`
-	DisableWindowFiltering            bool             `json:"disable-window-filtering"`
-	HighResolutionScrollingAware      bool             `json:"high-resolution-scrolling-aware"`
-	UltraHighResolutionScrollingAware bool             `json:"ultra-high-resolution-scrolling-aware"`
-	LongPathAware                     bool             `json:"long-path-aware"`
-	PrinterDriverIsolation            bool             `json:"printer-driver-isolation"`
-	GDIScaling                        bool             `json:"gdi-scaling"`
-	SegmentHeap                       bool             `json:"segment-heap"`
-	UseCommonControlsV6               bool             `json:"use-common-controls-v6"` // Application requires Common Controls V6 (V5 remains the default)
+	Identity                          AssemblyIdentity    `json:"identity"`
+	Description                       string              `json:"description"`
+	Compatibility                     SupportedOS         `json:"minimum-os"`
+	ExecutionLevel                    ExecutionLevel      `json:"execution-level"`
+	UIAccess                          bool                `json:"ui-access"` // Require access to other applications' UI elements
+	AutoElevate                       bool                `json:"auto-elevate"`
+	DPIAwareness                      DPIAwareness        `json:"dpi-awareness"`
+	DisableTheming                    bool                `json:"disable-theming"`
+	DisableWindowFiltering            bool                `json:"disable-window-filtering"`
+	HighResolutionScrollingAware      bool                `json:"high-resolution-scrolling-aware"`
+	UltraHighResolutionScrollingAware bool                `json:"ultra-high-resolution-scrolling-aware"`
+	LongPathAware                     bool                `json:"long-path-aware"`
+	PrinterDriverIsolation            bool                `json:"printer-driver-isolation"`
+	GDIScaling                        bool                `json:"gdi-scaling"`
+	SegmentHeap                       bool                `json:"segment-heap"`
+	UseCommonControlsV6               bool                `json:"use-common-controls-v6"`                    // Application requires Common Controls V6 (V5 remains the default)
+	ActiveCodePage                    string              `json:"active-code-page,omitempty"`                // "UTF-8", "Legacy", or a locale name such as "en-US"
+	MaxVersionTested                  string              `json:"max-version-tested,omitempty"`              // Highest Windows version the application was tested on, such as "10.0.18362.1"
+	SupportedArchitectures            string              `json:"supported-architectures,omitempty"`         // Space separated list, such as "amd64 arm64"
+	DisableFileSystemRedirection      bool                `json:"disable-file-system-redirection,omitempty"` // Disable WOW64 file system redirection
+	Dependencies                      []AssemblyReference `json:"dependencies,omitempty"`
+	// Registration-free COM
+	Files                          []ManifestFile          `json:"files,omitempty"`
+	COMInterfaceExternalProxyStubs []COMInterfaceProxyStub `json:"com-interface-external-proxy-stubs,omitempty"`
+	CLRClasses                     []CLRClass              `json:"clr-classes,omitempty"`
 }
 
 // AssemblyIdentity defines the side-by-side assembly identity of the executable.
@@ -43,11 +52,93 @@ type AssemblyIdentity struct {
 	Version [4]uint16
 }
 
+// AssemblyReference is a side-by-side assembly the executable depends on,
+// such as a private assembly or the Visual C++ runtime.
+//
+// Common Controls V6 should rather be declared with AppManifest.UseCommonControlsV6.
+//
+// Empty attributes are omitted, except Type which defaults to "win32".
+type AssemblyReference struct {
+	Name                  string
+	Version               [4]uint16
+	Type                  string
+	ProcessorArchitecture string // "*", "x86", "amd64", "arm64", ...
+	PublicKeyToken        string // Hexadecimal string, required for shared assemblies
+	Language              string // "*" for any language
+}
+
+// ManifestFile is a <file> element, which declares COM classes, type libraries and window classes
+// implemented by a DLL, for registration-free COM.
+//
+// Name is the path of the file, relative to the executable.
+type ManifestFile struct {
+	Name          string        `json:"name" xml:"name,attr"`
+	COMClasses    []COMClass    `json:"com-classes,omitempty" xml:"comClass"`
+	TypeLibs      []TypeLib     `json:"typelibs,omitempty" xml:"typelib"`
+	WindowClasses []WindowClass `json:"window-classes,omitempty" xml:"windowClass"`
+}
+
+// COMClass is a <comClass> element.
+//
+// CLSID and TLBID are GUIDs such as "{00000000-0000-0000-0000-000000000000}".
+type COMClass struct {
+	CLSID          string `json:"clsid" xml:"clsid,attr"`
+	ThreadingModel string `json:"threading-model,omitempty" xml:"threadingModel,attr,omitempty"` // "Apartment", "Free", "Both" or "Neutral"
+	ProgID         string `json:"progid,omitempty" xml:"progid,attr,omitempty"`
+	TLBID          string `json:"tlbid,omitempty" xml:"tlbid,attr,omitempty"`
+	Description    string `json:"description,omitempty" xml:"description,attr,omitempty"`
+}
+
+// TypeLib is a <typelib> element.
+type TypeLib struct {
+	TLBID      string `json:"tlbid" xml:"tlbid,attr"`
+	Version    string `json:"version" xml:"version,attr"` // Such as "1.0"
+	HelpDir    string `json:"help-dir" xml:"helpdir,attr"`
+	ResourceID string `json:"resource-id,omitempty" xml:"resourceid,attr,omitempty"`
+	Flags      string `json:"flags,omitempty" xml:"flags,attr,omitempty"`
+}
+
+// WindowClass is a <windowClass> element.
+//
+// Unless Unversioned is true, the class name is prefixed with the assembly version when it is registered.
+type WindowClass struct {
+	Name        string `json:"name"`
+	Unversioned bool   `json:"unversioned,omitempty"`
+}
+
+// COMInterfaceProxyStub is a <comInterfaceExternalProxyStub> element,
+// which declares an interface that is marshaled by an external proxy, such as the typelib marshaler.
+type COMInterfaceProxyStub struct {
+	IID              string `json:"iid" xml:"iid,attr"`
+	Name             string `json:"name,omitempty" xml:"name,attr,omitempty"`
+	TLBID            string `json:"tlbid,omitempty" xml:"tlbid,attr,omitempty"`
+	ProxyStubCLSID32 string `json:"proxy-stub-clsid32,omitempty" xml:"proxyStubClsid32,attr,omitempty"`
+	NumMethods       string `json:"num-methods,omitempty" xml:"numMethods,attr,omitempty"`
+	BaseInterface    string `json:"base-interface,omitempty" xml:"baseInterface,attr,omitempty"`
+}
+
+// CLRClass is a <clrClass> element, which declares a .NET class exposed to COM.
+type CLRClass struct {
+	CLSID          string `json:"clsid" xml:"clsid,attr"`
+	ProgID         string `json:"progid,omitempty" xml:"progid,attr,omitempty"`
+	ThreadingModel string `json:"threading-model,omitempty" xml:"threadingModel,attr,omitempty"`
+	Name           string `json:"name" xml:"name,attr"` // Full name of the .NET class
+	RuntimeVersion string `json:"runtime-version,omitempty" xml:"runtimeVersion,attr,omitempty"`
+	TLBID          string `json:"tlbid,omitempty" xml:"tlbid,attr,omitempty"`
+	Description    string `json:"description,omitempty" xml:"description,attr,omitempty"`
+}
+
 // DPIAwareness is an enumeration which corresponds to the <dpiAware> and the <dpiAwareness> elements.
 //
 // When it is set to DPIPerMonitorV2, it will fallback to DPIAware if the OS does not support it.
 //
 // DPIPerMonitor would not scale windows on secondary monitors.
+//
+// DPIUnawareGDIScaled lets Windows scale the application, but GDI text and shapes are rendered at the actual DPI,
+// so that they don't look blurry.
+//
+// DPIPerMonitorV2GDIScaled is the same as DPIPerMonitorV2, except it falls back to DPIUnawareGDIScaled
+// if the OS does not support per monitor v2.
 type DPIAwareness int
 
 const (
@@ -55,6 +146,8 @@ const (
 	DPIUnaware
 	DPIPerMonitor
 	DPIPerMonitorV2
+	DPIUnawareGDIScaled
+	DPIPerMonitorV2GDIScaled
 )
 
 // SupportedOS is an enumeration that provides a simplified way to fill the
@@ -103,6 +196,9 @@ var manifestTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
 
   <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
     <application>
+      {{- if .MaxVersionTested}}
+      <maxversiontested Id="{{.MaxVersionTested | html}}"/>
+      {{- end}}
       {{- range $osID := .SupportedOS}}
       <supportedOS Id="{{$osID}}"/>
       {{- end}}
@@ -140,6 +236,15 @@ var manifestTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
       {{- if .SegmentHeap}}
       <heapType xmlns="http://schemas.microsoft.com/SMI/2020/WindowsSettings">SegmentHeap</heapType>
       {{- end}}
+      {{- if .ActiveCodePage}}
+      <activeCodePage xmlns="http://schemas.microsoft.com/SMI/2019/WindowsSettings">{{.ActiveCodePage | html}}</activeCodePage>
+      {{- end}}
+      {{- if .SupportedArchitectures}}
+      <supportedArchitectures xmlns="http://schemas.microsoft.com/SMI/2024/WindowsSettings">{{.SupportedArchitectures | html}}</supportedArchitectures>
+      {{- end}}
+      {{- if .DisableFileSystemRedirection}}
+      <disableFileSystemRedirection xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true</disableFileSystemRedirection>
+      {{- end}}
     </windowsSettings>
   </application>
 
@@ -158,6 +263,61 @@ var manifestTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
     </dependentAssembly>
   </dependency>
   {{- end}}
+  {{- range .DependentAssemblies}}
+
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="{{.Type | html}}" name="{{.Name | html}}" version="{{.Version}}"
+        {{- attr "processorArchitecture" .ProcessorArchitecture}}
+        {{- attr "publicKeyToken" .PublicKeyToken}}
+        {{- attr "language" .Language}}/>
+    </dependentAssembly>
+  </dependency>
+  {{- end}}
+  {{- if .Files}}
+{{range .Files}}
+  <file name="{{.Name | html}}"
+  {{- if not (or .COMClasses .TypeLibs .WindowClasses)}}/>
+  {{- else}}>
+    {{- range .COMClasses}}
+    <comClass clsid="{{.CLSID | html}}"
+      {{- attr "threadingModel" .ThreadingModel}}
+      {{- attr "progid" .ProgID}}
+      {{- attr "tlbid" .TLBID}}
+      {{- attr "description" .Description}}/>
+    {{- end}}
+    {{- range .TypeLibs}}
+    <typelib tlbid="{{.TLBID | html}}" version="{{.Version | html}}" helpdir="{{.HelpDir | html}}"
+      {{- attr "resourceid" .ResourceID}}
+      {{- attr "flags" .Flags}}/>
+    {{- end}}
+    {{- range .WindowClasses}}
+    <windowClass{{if .Unversioned}} versioned="no"{{end}}>{{.Name | html}}</windowClass>
+    {{- end}}
+  </file>
+  {{- end}}
+  {{- end}}
+  {{- end}}
+  {{- if .COMInterfaceExternalProxyStubs}}
+{{range .COMInterfaceExternalProxyStubs}}
+  <comInterfaceExternalProxyStub iid="{{.IID | html}}"
+    {{- attr "name" .Name}}
+    {{- attr "tlbid" .TLBID}}
+    {{- attr "proxyStubClsid32" .ProxyStubCLSID32}}
+    {{- attr "numMethods" .NumMethods}}
+    {{- attr "baseInterface" .BaseInterface}}/>
+  {{- end}}
+  {{- end}}
+  {{- if .CLRClasses}}
+{{range .CLRClasses}}
+  <clrClass clsid="{{.CLSID | html}}" name="{{.Name | html}}"
+    {{- attr "progid" .ProgID}}
+    {{- attr "threadingModel" .ThreadingModel}}
+    {{- attr "runtimeVersion" .RuntimeVersion}}
+    {{- attr "tlbid" .TLBID}}
+    {{- attr "description" .Description}}/>
+  {{- end}}
+  {{- end}}
 
 </assembly>
 `
@@ -171,12 +331,21 @@ func makeManifest(manifest AppManifest) []byte {
 		DPIAware        string
 		DPIAwareness    string
 		ExecutionLevel  string
+		// Same as Dependencies, with a version string and a default type
+		DependentAssemblies []assemblyReferenceJSON
 	}{AppManifest: manifest}
 
 	if manifest.Identity.Name != "" {
 		vars.AssemblyName = manifest.Identity.Name
-		v := manifest.Identity.Version
-		vars.AssemblyVersion = fmt.Sprintf("%d.%d.%d.%d", v[0], v[1], v[2], v[3])
+		vars.AssemblyVersion = assemblyVersionString(manifest.Identity.Version)
+	}
+
+	for _, dep := range manifest.Dependencies {
+		d := dep.toJSON()
+		if d.Type == "" {
+			d.Type = "win32"
+		}
+		vars.DependentAssemblies = append(vars.DependentAssemblies, d)
 	}
 
 	vars.SupportedOS = []string{
@@ -221,10 +390,19 @@ func makeManifest(manifest AppManifest) []byte {
 	case DPIUnaware:
 		vars.DPIAware = "false"
 		vars.DPIAwareness = "unaware"
+	case DPIUnawareGDIScaled:
+		vars.DPIAware = "false"
+		vars.DPIAwareness = "unaware"
+		vars.GDIScaling = true
+	case DPIPerMonitorV2GDIScaled:
+		// gdiScaling only applies to unaware applications, so it is only effective when falling back
+		vars.DPIAware = "false"
+		vars.DPIAwareness = "permonitorv2,unaware"
+		vars.GDIScaling = true
 	}
 
 	buf := &bytes.Buffer{}
-	tmpl := template.Must(template.New("manifest").Parse(manifestTemplate))
+	tmpl := template.Must(template.New("manifest").Funcs(template.FuncMap{"attr": manifestAttr}).Parse(manifestTemplate))
 	err := tmpl.Execute(buf, vars)
 	if err != nil {
 		panic(err)
@@ -233,6 +411,14 @@ func makeManifest(manifest AppManifest) []byte {
 	return buf.Bytes()
 }
 
+// manifestAttr renders an optional xml attribute, with a leading space.
+func manifestAttr(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return " " + name + `="` + template.HTMLEscapeString(value) + `"`
+}
+
 type appManifestXML struct {
 	Identity struct {
 		Name    string `xml:"name,attr"`
@@ -241,6 +427,9 @@ type appManifestXML struct {
 	Description   string `xml:"description"`
 	Compatibility struct {
 		Application struct {
+			MaxVersionTested []struct {
+				Id string `xml:"Id,attr"`
+			} `xml:"maxversiontested"`
 			SupportedOS []struct {
 				Id string `xml:"Id,attr"`
 			} `xml:"supportedOS"`
@@ -259,6 +448,9 @@ type appManifestXML struct {
 			LongPathAware                     string `xml:"longPathAware"`
 			GDIScaling                        string `xml:"gdiScaling"`
 			HeapType                          string `xml:"heapType"`
+			ActiveCodePage                    string `xml:"activeCodePage"`
+			SupportedArchitectures            string `xml:"supportedArchitectures"`
+			DisableFileSystemRedirection      string `xml:"disableFileSystemRedirection"`
 		} `xml:"windowsSettings"`
 	} `xml:"application"`
 	TrustInfo struct {
@@ -274,12 +466,26 @@ type appManifestXML struct {
 	Dependency struct {
 		DependentAssembly []struct {
 			Identity struct {
-				Name           string `xml:"name,attr"`
-				Version        string `xml:"version,attr"`
-				PublicKeyToken string `xml:"publicKeyToken,attr"`
+				Name                  string `xml:"name,attr"`
+				Version               string `xml:"version,attr"`
+				Type                  string `xml:"type,attr"`
+				ProcessorArchitecture string `xml:"processorArchitecture,attr"`
+				PublicKeyToken        string `xml:"publicKeyToken,attr"`
+				Language              string `xml:"language,attr"`
 			} `xml:"assemblyIdentity"`
 		} `xml:"dependentAssembly"`
 	} `xml:"dependency"`
+	File []struct {
+		Name          string     `xml:"name,attr"`
+		COMClasses    []COMClass `xml:"comClass"`
+		TypeLibs      []TypeLib  `xml:"typelib"`
+		WindowClasses []struct {
+			Name      string `xml:",chardata"`
+			Versioned string `xml:"versioned,attr"`
+		} `xml:"windowClass"`
+	} `xml:"file"`
+	COMInterfaceExternalProxyStubs []COMInterfaceProxyStub `xml:"comInterfaceExternalProxyStub"`
+	CLRClasses                     []CLRClass              `xml:"clrClass"`
 }
 
 // AppManifestFromXML makes an AppManifest from an xml manifest,
@@ -297,14 +503,7 @@ func AppManifestFromXML(data []byte) (AppManifest, error) {
 	var m AppManifest
 
 	m.Identity.Name = x.Identity.Name
-	v := strings.Split(x.Identity.Version, ".")
-	if len(v) > 4 {
-		v = v[:4]
-	}
-	for i := range v {
-		n, _ := strconv.ParseUint(v[i], 10, 16)
-		m.Identity.Version[i] = uint16(n)
-	}
+	m.Identity.Version = readAssemblyVersion(x.Identity.Version)
 	m.Description = x.Description
 
 	m.Compatibility = Win10AndAbove + 1
@@ -317,6 +516,12 @@ func AppManifestFromXML(data []byte) (AppManifest, error) {
 	if m.Compatibility > Win10AndAbove {
 		m.Compatibility = Win7AndAbove
 	}
+	// There may be one element per OS family, but only desktop Windows is relevant here
+	for _, v := range x.Compatibility.Application.MaxVersionTested {
+		if m.MaxVersionTested == "" || strings.HasPrefix(strings.TrimSpace(v.Id), "10.") {
+			m.MaxVersionTested = strings.TrimSpace(v.Id)
+		}
+	}
 
 	settings := x.Application.WindowsSettings
 	m.DPIAwareness = readDPIAwareness(settings.DPIAware, settings.DPIAwareness)
@@ -329,15 +534,58 @@ func AppManifestFromXML(data []byte) (AppManifest, error) {
 	m.LongPathAware = manifestBool(settings.LongPathAware)
 	m.GDIScaling = manifestBool(settings.GDIScaling)
 	m.SegmentHeap = manifestString(settings.HeapType) == "segmentheap"
+	m.ActiveCodePage = strings.TrimSpace(settings.ActiveCodePage)
+	m.SupportedArchitectures = strings.Join(strings.Fields(settings.SupportedArchitectures), " ")
+	m.DisableFileSystemRedirection = manifestBool(settings.DisableFileSystemRedirection)
+	if m.GDIScaling {
+		// GDI scaling variants are stored in DPIAwareness
+		switch {
+		case m.DPIAwareness == DPIUnaware:
+			m.DPIAwareness = DPIUnawareGDIScaled
+			m.GDIScaling = false
+		case m.DPIAwareness == DPIPerMonitorV2 && readDPIAwarenessFallback(settings.DPIAware, settings.DPIAwareness) == DPIUnaware:
+			m.DPIAwareness = DPIPerMonitorV2GDIScaled
+			m.GDIScaling = false
+		}
+	}
 
 	for _, dep := range x.Dependency.DependentAssembly {
 		if manifestString(dep.Identity.Name) == "microsoft.windows.common-controls" &&
 			strings.HasPrefix(manifestString(dep.Identity.Version), "6.") &&
 			manifestString(dep.Identity.PublicKeyToken) == "6595b64144ccf1df" {
 			m.UseCommonControlsV6 = true
+			continue
+		}
+		if strings.TrimSpace(dep.Identity.Name) == "" {
+			continue
 		}
+		m.Dependencies = append(m.Dependencies, AssemblyReference{
+			Name:                  strings.TrimSpace(dep.Identity.Name),
+			Version:               readAssemblyVersion(dep.Identity.Version),
+			Type:                  strings.TrimSpace(dep.Identity.Type),
+			ProcessorArchitecture: strings.TrimSpace(dep.Identity.ProcessorArchitecture),
+			PublicKeyToken:        strings.TrimSpace(dep.Identity.PublicKeyToken),
+			Language:              strings.TrimSpace(dep.Identity.Language),
+		})
 	}
 
+	for _, f := range x.File {
+		file := ManifestFile{
+			Name:       strings.TrimSpace(f.Name),
+			COMClasses: f.COMClasses,
+			TypeLibs:   f.TypeLibs,
+		}
+		for _, wc := range f.WindowClasses {
+			file.WindowClasses = append(file.WindowClasses, WindowClass{
+				Name:        strings.TrimSpace(wc.Name),
+				Unversioned: manifestString(wc.Versioned) == "no",
+			})
+		}
+		m.Files = append(m.Files, file)
+	}
+	m.COMInterfaceExternalProxyStubs = x.COMInterfaceExternalProxyStubs
+	m.CLRClasses = x.CLRClasses
+
 	m.UIAccess = manifestBool(x.TrustInfo.Security.RequestedPrivileges.RequestedExecutionLevel.UIAccess)
 	switch manifestString(x.TrustInfo.Security.RequestedPrivileges.RequestedExecutionLevel.Level) {
 	case "requireadministrator":
@@ -349,6 +597,24 @@ func AppManifestFromXML(data []byte) (AppManifest, error) {
 	return m, nil
 }
 
+// readAssemblyVersion reads a version attribute, ignoring errors.
+func readAssemblyVersion(s string) [4]uint16 {
+	var version [4]uint16
+	v := strings.Split(s, ".")
+	if len(v) > 4 {
+		v = v[:4]
+	}
+	for i := range v {
+		n, _ := strconv.ParseUint(v[i], 10, 16)
+		version[i] = uint16(n)
+	}
+	return version
+}
+
+func assemblyVersionString(v [4]uint16) string {
+	return fmt.Sprintf("%d.%d.%d.%d", v[0], v[1], v[2], v[3])
+}
+
 func readDPIAwareness(dpiAware string, dpiAwareness string) DPIAwareness {
 	for _, s := range strings.Split(dpiAwareness, ",") {
 		switch manifestString(s) {
@@ -371,6 +637,14 @@ func readDPIAwareness(dpiAware string, dpiAwareness string) DPIAwareness {
 	return DPIUnaware
 }
 
+// readDPIAwarenessFallback returns the DPI awareness Windows would use if the first value in <dpiAwareness> was not supported.
+func readDPIAwarenessFallback(dpiAware string, dpiAwareness string) DPIAwareness {
+	if i := strings.IndexByte(dpiAwareness, ','); i >= 0 {
+		return readDPIAwareness(dpiAware, dpiAwareness[i+1:])
+	}
+	return readDPIAwareness(dpiAware, "")
+}
+
 func manifestString(s string) string {
 	return strings.ToLower(strings.TrimSpace(s))
 }
@@ -442,6 +716,10 @@ func (a DPIAwareness) MarshalText() ([]byte, error) {
 		return []byte("per monitor"), nil
 	case DPIPerMonitorV2:
 		return []byte("per monitor v2"), nil
+	case DPIUnawareGDIScaled:
+		return []byte("unaware gdi scaled"), nil
+	case DPIPerMonitorV2GDIScaled:
+		return []byte("per monitor v2 gdi scaled"), nil
 	}
 	return nil, errors.New(errUnknownDPIAwareness)
 }
@@ -460,6 +738,12 @@ func (a *DPIAwareness) UnmarshalText(b []byte) error {
 	case "per monitor v2", "permonitorv2":
 		*a = DPIPerMonitorV2
 		return nil
+	case "unaware gdi scaled":
+		*a = DPIUnawareGDIScaled
+		return nil
+	case "per monitor v2 gdi scaled":
+		*a = DPIPerMonitorV2GDIScaled
+		return nil
 	}
 	return errors.New(errUnknownDPIAwareness)
 }
@@ -503,7 +787,7 @@ func (ai AssemblyIdentity) MarshalJSON() ([]byte, error) {
 	j := assemblyIdentityJSON{}
 	j.Name = ai.Name
 	if ai.Name != "" {
-		j.Version = fmt.Sprintf("%d.%d.%d.%d", ai.Version[0], ai.Version[1], ai.Version[2], ai.Version[3])
+		j.Version = assemblyVersionString(ai.Version)
 	}
 	return json.Marshal(j)
 }
@@ -514,11 +798,17 @@ func (ai *AssemblyIdentity) UnmarshalJSON(b []byte) error {
 		return err
 	}
 	ai.Name = j.Name
-	j.Version = strings.TrimSpace(j.Version)
-	if j.Version == "" {
+	return parseAssemblyVersion(j.Version, &ai.Version)
+}
+
+// parseAssemblyVersion parses a version string from a JSON file.
+// Unlike readAssemblyVersion, it returns an error if the version is invalid.
+func parseAssemblyVersion(s string, version *[4]uint16) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
 		return nil
 	}
-	v := strings.Split(j.Version, ".")
+	v := strings.Split(s, ".")
 	if len(v) > 4 {
 		return errors.New(errInvalidVersion)
 	}
@@ -527,7 +817,46 @@ func (ai *AssemblyIdentity) UnmarshalJSON(b []byte) error {
 		if err != nil {
 			return errors.New(errInvalidVersion)
 		}
-		ai.Version[i] = uint16(n)
+		version[i] = uint16(n)
 	}
 	return nil
 }
+
+type assemblyReferenceJSON struct {
+	Name                  string `json:"name"`
+	Version               string `json:"version"`
+	Type                  string `json:"type,omitempty"`
+	ProcessorArchitecture string `json:"processor-architecture,omitempty"`
+	PublicKeyToken        string `json:"public-key-token,omitempty"`
+	Language              string `json:"language,omitempty"`
+}
+
+func (ar AssemblyReference) toJSON() assemblyReferenceJSON {
+	return assemblyReferenceJSON{
+		Name:                  ar.Name,
+		Version:               assemblyVersionString(ar.Version),
+		Type:                  ar.Type,
+		ProcessorArchitecture: ar.ProcessorArchitecture,
+		PublicKeyToken:        ar.PublicKeyToken,
+		Language:              ar.Language,
+	}
+}
+
+func (ar AssemblyReference) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ar.toJSON())
+}
+
+func (ar *AssemblyReference) UnmarshalJSON(b []byte) error {
+	j := assemblyReferenceJSON{}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*ar = AssemblyReference{
+		Name:                  j.Name,
+		Type:                  j.Type,
+		ProcessorArchitecture: j.ProcessorArchitecture,
+		PublicKeyToken:        j.PublicKeyToken,
+		Language:              j.Language,
+	}
+	return parseAssemblyVersion(j.Version, &ar.Version)
+}
@@ -291,6 +291,144 @@ Description</description>
     </security>
   </trustInfo>
 
+</assembly>
+`},
+		{
+			name: "modern",
+			args: struct{ manifest AppManifest }{AppManifest{
+				Compatibility:                Win10AndAbove,
+				DPIAwareness:                 DPIPerMonitorV2GDIScaled,
+				ActiveCodePage:               "UTF-8",
+				MaxVersionTested:             "10.0.22621.1",
+				SupportedArchitectures:       "amd64 arm64",
+				DisableFileSystemRedirection: true,
+			}},
+			// language=manifest
+			want: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <maxversiontested Id="10.0.22621.1"/>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">false</dpiAware>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">permonitorv2,unaware</dpiAwareness>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+      <activeCodePage xmlns="http://schemas.microsoft.com/SMI/2019/WindowsSettings">UTF-8</activeCodePage>
+      <supportedArchitectures xmlns="http://schemas.microsoft.com/SMI/2024/WindowsSettings">amd64 arm64</supportedArchitectures>
+      <disableFileSystemRedirection xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true</disableFileSystemRedirection>
+    </windowsSettings>
+  </application>
+
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+
+</assembly>
+`},
+		{
+			name: "unawareGDIScaled",
+			args: struct{ manifest AppManifest }{AppManifest{
+				Compatibility: Win10AndAbove,
+				DPIAwareness:  DPIUnawareGDIScaled,
+			}},
+			// language=manifest
+			want: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">false</dpiAware>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">unaware</dpiAwareness>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+    </windowsSettings>
+  </application>
+
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+
+</assembly>
+`},
+		{
+			name: "dependencies",
+			args: struct{ manifest AppManifest }{AppManifest{
+				Compatibility: Win10AndAbove,
+				Dependencies: []AssemblyReference{
+					{Name: "Private.Assembly", Version: [4]uint16{1, 2, 3, 4}},
+					{
+						Name:                  "Microsoft.VC90.CRT",
+						Version:               [4]uint16{9, 0, 21022, 8},
+						Type:                  "win32",
+						ProcessorArchitecture: "amd64",
+						PublicKeyToken:        "1fc8b3b9a1e18e3b",
+						Language:              "*",
+					},
+				},
+				UseCommonControlsV6: true,
+			}},
+			// language=manifest
+			want: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true</dpiAware>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">system</dpiAwareness>
+    </windowsSettings>
+  </application>
+
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Microsoft.Windows.Common-Controls" version="6.0.0.0" processorArchitecture="*" publicKeyToken="6595b64144ccf1df" language="*"/>
+    </dependentAssembly>
+  </dependency>
+
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Private.Assembly" version="1.2.3.4"/>
+    </dependentAssembly>
+  </dependency>
+
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Microsoft.VC90.CRT" version="9.0.21022.8" processorArchitecture="amd64" publicKeyToken="1fc8b3b9a1e18e3b" language="*"/>
+    </dependentAssembly>
+  </dependency>
+
 </assembly>
 `},
 	}
@@ -457,6 +595,14 @@ func TestAppManifestFromXML(t *testing.T) {
 				LongPathAware:                     true,
 				GDIScaling:                        true,
 				UseCommonControlsV6:               true,
+				Dependencies: []AssemblyReference{{
+					Name:                  "a",
+					Version:               [4]uint16{5, 6, 6, 6},
+					Type:                  "win32",
+					ProcessorArchitecture: "*",
+					PublicKeyToken:        "42",
+					Language:              "*",
+				}},
 			},
 			wantErr: false,
 		},
@@ -543,6 +689,84 @@ func TestAppManifestFromXML(t *testing.T) {
 			want:    AppManifest{Compatibility: WinVistaAndAbove},
 			wantErr: false,
 		},
+		{
+			name: "modern", xml: // language=manifest
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <maxversiontested Id="6.3.0.0"/>
+      <maxversiontested Id=" 10.0.18362.1 "/>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">PerMonitorV2, Unaware</dpiAwareness>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+      <activeCodePage xmlns="http://schemas.microsoft.com/SMI/2019/WindowsSettings"> UTF-8 </activeCodePage>
+      <supportedArchitectures xmlns="http://schemas.microsoft.com/SMI/2024/WindowsSettings">
+        amd64   arm64
+      </supportedArchitectures>
+      <disableFileSystemRedirection xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">TRUE</disableFileSystemRedirection>
+    </windowsSettings>
+  </application>
+</assembly>`,
+			want: AppManifest{
+				Compatibility:                Win10AndAbove,
+				DPIAwareness:                 DPIPerMonitorV2GDIScaled,
+				ActiveCodePage:               "UTF-8",
+				MaxVersionTested:             "10.0.18362.1",
+				SupportedArchitectures:       "amd64 arm64",
+				DisableFileSystemRedirection: true,
+			},
+		},
+		{
+			name: "unawareGDIScaled", xml: // language=manifest
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware>false</dpiAware>
+      <gdiScaling>true</gdiScaling>
+    </windowsSettings>
+  </application>
+</assembly>`,
+			want: AppManifest{DPIAwareness: DPIUnawareGDIScaled},
+		},
+		{
+			name: "systemGDIScaled", xml: // language=manifest
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAwareness>permonitorv2,system</dpiAwareness>
+      <gdiScaling>true</gdiScaling>
+    </windowsSettings>
+  </application>
+</assembly>`,
+			want: AppManifest{DPIAwareness: DPIPerMonitorV2, GDIScaling: true},
+		},
+		{
+			name: "dependencies", xml: // language=manifest
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity name=" Private.Assembly " version="1.2"/>
+    </dependentAssembly>
+  </dependency>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity name=""/>
+    </dependentAssembly>
+  </dependency>
+</assembly>`,
+			want: AppManifest{
+				DPIAwareness: DPIUnaware,
+				Dependencies: []AssemblyReference{{Name: "Private.Assembly", Version: [4]uint16{1, 2}}},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -667,6 +891,27 @@ func Test_AppManifest_MarshalJSON(t *testing.T) {
 			want:// language=json
 			`{"identity":{},"description":"","minimum-os":"win10","execution-level":"","ui-access":true,"auto-elevate":false,"dpi-awareness":"system","disable-theming":false,"disable-window-filtering":true,"high-resolution-scrolling-aware":false,"ultra-high-resolution-scrolling-aware":true,"long-path-aware":false,"printer-driver-isolation":true,"gdi-scaling":false,"segment-heap":true,"use-common-controls-v6":false}`,
 		},
+		{
+			manifest: AppManifest{
+				DPIAwareness:                 DPIUnawareGDIScaled,
+				ActiveCodePage:               "UTF-8",
+				MaxVersionTested:             "10.0.18362.1",
+				SupportedArchitectures:       "amd64 arm64",
+				DisableFileSystemRedirection: true,
+			},
+			want:// language=json
+			`{"identity":{},"description":"","minimum-os":"win7","execution-level":"","ui-access":false,"auto-elevate":false,"dpi-awareness":"unaware gdi scaled","disable-theming":false,"disable-window-filtering":false,"high-resolution-scrolling-aware":false,"ultra-high-resolution-scrolling-aware":false,"long-path-aware":false,"printer-driver-isolation":false,"gdi-scaling":false,"segment-heap":false,"use-common-controls-v6":false,"active-code-page":"UTF-8","max-version-tested":"10.0.18362.1","supported-architectures":"amd64 arm64","disable-file-system-redirection":true}`,
+		},
+		{
+			manifest: AppManifest{
+				Dependencies: []AssemblyReference{
+					{Name: "a", Version: [4]uint16{1, 2, 3, 4}},
+					{Name: "b", Type: "win32", ProcessorArchitecture: "*", PublicKeyToken: "0123456789abcdef", Language: "*"},
+				},
+			},
+			want:// language=json
+			`{"identity":{},"description":"","minimum-os":"win7","execution-level":"","ui-access":false,"auto-elevate":false,"dpi-awareness":"system","disable-theming":false,"disable-window-filtering":false,"high-resolution-scrolling-aware":false,"ultra-high-resolution-scrolling-aware":false,"long-path-aware":false,"printer-driver-isolation":false,"gdi-scaling":false,"segment-heap":false,"use-common-controls-v6":false,"dependencies":[{"name":"a","version":"1.2.3.4"},{"name":"b","version":"0.0.0.0","type":"win32","processor-architecture":"*","public-key-token":"0123456789abcdef","language":"*"}]}`,
+		},
 		{
 			manifest: AppManifest{Compatibility: 42},
 			wantErr:  errUnknownSupportedOS,
@@ -792,6 +1037,37 @@ func Test_AppManifest_UnmarshalJSON(t *testing.T) {
 				UseCommonControlsV6: true,
 			},
 		},
+		{
+			json:// language=json
+			`{"dpi-awareness":"per monitor v2 gdi scaled","active-code-page":"Legacy","max-version-tested":"10.0.22000.1","supported-architectures":"arm64","disable-file-system-redirection":true}`,
+			want: AppManifest{
+				DPIAwareness:                 DPIPerMonitorV2GDIScaled,
+				ActiveCodePage:               "Legacy",
+				MaxVersionTested:             "10.0.22000.1",
+				SupportedArchitectures:       "arm64",
+				DisableFileSystemRedirection: true,
+			},
+		},
+		{
+			json:// language=json
+			`{"dependencies":[{"name":"a","version":" 1.2.3.4 ","processor-architecture":"x86","language":"*"},{"name":"b"}]}`,
+			want: AppManifest{
+				Dependencies: []AssemblyReference{
+					{Name: "a", Version: [4]uint16{1, 2, 3, 4}, ProcessorArchitecture: "x86", Language: "*"},
+					{Name: "b"},
+				},
+			},
+		},
+		{
+			json:// language=json
+			`{"dependencies":[{"name":"a","version":"1.2.3.4.5"}]}`,
+			wantErr: errInvalidVersion,
+		},
+		{
+			json:// language=json
+			`{"dependencies":[["a"]]}`,
+			wantErr: "*",
+		},
 		{
 			json:// language=json
 			`{"identity":{"version":"1.65536.1.1"}}`,
@@ -851,3 +1127,117 @@ func Test_AppManifest_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestAppManifest_RegFreeCOM(t *testing.T) {
+	manifest := AppManifest{
+		Compatibility: Win10AndAbove,
+		Files: []ManifestFile{
+			{
+				Name: "component.dll",
+				COMClasses: []COMClass{
+					{
+						CLSID:          "{11111111-1111-1111-1111-111111111111}",
+						ThreadingModel: "Apartment",
+						ProgID:         "Component.Object",
+						TLBID:          "{22222222-2222-2222-2222-222222222222}",
+						Description:    "Component <Object>",
+					},
+					{CLSID: "{33333333-3333-3333-3333-333333333333}"},
+				},
+				TypeLibs: []TypeLib{
+					{TLBID: "{22222222-2222-2222-2222-222222222222}", Version: "1.0", Flags: "HASDISKIMAGE"},
+				},
+				WindowClasses: []WindowClass{
+					{Name: "ComponentWindow"},
+					{Name: "GlobalWindow", Unversioned: true},
+				},
+			},
+			{Name: "empty.dll"},
+		},
+		COMInterfaceExternalProxyStubs: []COMInterfaceProxyStub{
+			{
+				IID:              "{44444444-4444-4444-4444-444444444444}",
+				Name:             "IComponent",
+				TLBID:            "{22222222-2222-2222-2222-222222222222}",
+				ProxyStubCLSID32: "{00020424-0000-0000-C000-000000000046}",
+			},
+		},
+		CLRClasses: []CLRClass{
+			{
+				CLSID:          "{55555555-5555-5555-5555-555555555555}",
+				ProgID:         "Managed.Object",
+				ThreadingModel: "Both",
+				Name:           "Managed.Object",
+				RuntimeVersion: "v4.0.30319",
+			},
+		},
+	}
+
+	// language=manifest
+	want := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true</dpiAware>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">system</dpiAwareness>
+    </windowsSettings>
+  </application>
+
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+
+  <file name="component.dll">
+    <comClass clsid="{11111111-1111-1111-1111-111111111111}" threadingModel="Apartment" progid="Component.Object" tlbid="{22222222-2222-2222-2222-222222222222}" description="Component &lt;Object&gt;"/>
+    <comClass clsid="{33333333-3333-3333-3333-333333333333}"/>
+    <typelib tlbid="{22222222-2222-2222-2222-222222222222}" version="1.0" helpdir="" flags="HASDISKIMAGE"/>
+    <windowClass>ComponentWindow</windowClass>
+    <windowClass versioned="no">GlobalWindow</windowClass>
+  </file>
+  <file name="empty.dll"/>
+
+  <comInterfaceExternalProxyStub iid="{44444444-4444-4444-4444-444444444444}" name="IComponent" tlbid="{22222222-2222-2222-2222-222222222222}" proxyStubClsid32="{00020424-0000-0000-C000-000000000046}"/>
+
+  <clrClass clsid="{55555555-5555-5555-5555-555555555555}" name="Managed.Object" progid="Managed.Object" threadingModel="Both" runtimeVersion="v4.0.30319"/>
+
+</assembly>
+`
+	xmlData := makeManifest(manifest)
+	if string(xmlData) != want {
+		t.Errorf("*** makeManifest():\n%v###\n*** want:\n%v###", string(xmlData), want)
+	}
+
+	got, err := AppManifestFromXML(xmlData)
+	if err != nil || !reflect.DeepEqual(got, manifest) {
+		t.Errorf("AppManifestFromXML() got = %v, %v, want %v", got, err, manifest)
+	}
+
+	j, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// language=json
+	wantJSON := `{"identity":{},"description":"","minimum-os":"win10","execution-level":"","ui-access":false,"auto-elevate":false,"dpi-awareness":"system","disable-theming":false,"disable-window-filtering":false,"high-resolution-scrolling-aware":false,"ultra-high-resolution-scrolling-aware":false,"long-path-aware":false,"printer-driver-isolation":false,"gdi-scaling":false,"segment-heap":false,"use-common-controls-v6":false,` +
+		`"files":[{"name":"component.dll","com-classes":[{"clsid":"{11111111-1111-1111-1111-111111111111}","threading-model":"Apartment","progid":"Component.Object","tlbid":"{22222222-2222-2222-2222-222222222222}","description":"Component \u003cObject\u003e"},{"clsid":"{33333333-3333-3333-3333-333333333333}"}],"typelibs":[{"tlbid":"{22222222-2222-2222-2222-222222222222}","version":"1.0","help-dir":"","flags":"HASDISKIMAGE"}],"window-classes":[{"name":"ComponentWindow"},{"name":"GlobalWindow","unversioned":true}]},{"name":"empty.dll"}],` +
+		`"com-interface-external-proxy-stubs":[{"iid":"{44444444-4444-4444-4444-444444444444}","name":"IComponent","tlbid":"{22222222-2222-2222-2222-222222222222}","proxy-stub-clsid32":"{00020424-0000-0000-C000-000000000046}"}],` +
+		`"clr-classes":[{"clsid":"{55555555-5555-5555-5555-555555555555}","progid":"Managed.Object","threading-model":"Both","name":"Managed.Object","runtime-version":"v4.0.30319"}]}`
+	if string(j) != wantJSON {
+		t.Errorf("json.Marshal(AppManifest):\n%s\nwant:\n%s", string(j), wantJSON)
+	}
+
+	var fromJSON AppManifest
+	if err = json.Unmarshal(j, &fromJSON); err != nil || !reflect.DeepEqual(fromJSON, manifest) {
+		t.Errorf("json.Unmarshal(AppManifest) got = %v, %v, want %v", fromJSON, err, manifest)
+	}
+}
@@ -0,0 +1,575 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Manifest is an application manifest document.
+//
+// Unlike AppManifest, it keeps the original xml, so that a setting can be changed
+// without losing unknown elements, comments, namespaces and ordering.
+//
+// Elements are found by their local name, like AppManifestFromXML does.
+// New elements are created in their usual namespace.
+type Manifest struct {
+	bom  bool
+	doc  *xmlElement // Pseudo element holding the prolog, the root element, and the epilog
+	root *xmlElement
+}
+
+// xmlElement is a node of a minimal DOM that preserves prefixes and namespace declarations.
+//
+// Its name and attributes are raw, which means Space holds a prefix, not a namespace.
+type xmlElement struct {
+	name     xml.Name
+	attr     []xml.Attr
+	children []xml.Token // xml.CharData, xml.Comment, xml.ProcInst, xml.Directive or *xmlElement
+	parent   *xmlElement
+}
+
+const (
+	nsAsmV1         = "urn:schemas-microsoft-com:asm.v1"
+	nsAsmV3         = "urn:schemas-microsoft-com:asm.v3"
+	nsCompatibility = "urn:schemas-microsoft-com:compatibility.v1"
+	nsSettings2005  = "http://schemas.microsoft.com/SMI/2005/WindowsSettings"
+	nsSettings2011  = "http://schemas.microsoft.com/SMI/2011/WindowsSettings"
+	nsSettings2013  = "http://schemas.microsoft.com/SMI/2013/WindowsSettings"
+	nsSettings2016  = "http://schemas.microsoft.com/SMI/2016/WindowsSettings"
+	nsSettings2017  = "http://schemas.microsoft.com/SMI/2017/WindowsSettings"
+	nsSettings2019  = "http://schemas.microsoft.com/SMI/2019/WindowsSettings"
+	nsSettings2020  = "http://schemas.microsoft.com/SMI/2020/WindowsSettings"
+	nsSettings2024  = "http://schemas.microsoft.com/SMI/2024/WindowsSettings"
+)
+
+// windowsSettingsNamespaces maps known <windowsSettings> elements to their namespace.
+var windowsSettingsNamespaces = map[string]string{
+	"autoElevate":                       nsSettings2005,
+	"disableTheming":                    nsSettings2005,
+	"disableFileSystemRedirection":      nsSettings2005,
+	"dpiAware":                          nsSettings2005,
+	"disableWindowFiltering":            nsSettings2011,
+	"printerDriverIsolation":            nsSettings2011,
+	"highResolutionScrollingAware":      nsSettings2013,
+	"ultraHighResolutionScrollingAware": nsSettings2013,
+	"dpiAwareness":                      nsSettings2016,
+	"longPathAware":                     nsSettings2016,
+	"gdiScaling":                        nsSettings2017,
+	"activeCodePage":                    nsSettings2019,
+	"heapType":                          nsSettings2020,
+	"supportedArchitectures":            nsSettings2024,
+}
+
+// ParseManifest parses an xml manifest into a Manifest document.
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{doc: &xmlElement{}}
+	if bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")) {
+		m.bom = true
+		data = data[3:]
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	current := m.doc
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &xmlElement{name: t.Name, attr: t.Copy().Attr, parent: current}
+			current.children = append(current.children, e)
+			if current == m.doc {
+				if m.root != nil {
+					return nil, errors.New(errInvalidManifest)
+				}
+				m.root = e
+			}
+			current = e
+		case xml.EndElement:
+			if current == m.doc || current.name != t.Name {
+				return nil, &xml.SyntaxError{Msg: "unexpected end element </" + rawName(t.Name) + ">", Line: lineOf(data, d.InputOffset())}
+			}
+			current = current.parent
+		default:
+			current.children = append(current.children, xml.CopyToken(tok))
+		}
+	}
+
+	if current != m.doc {
+		return nil, &xml.SyntaxError{Msg: "unexpected EOF", Line: lineOf(data, d.InputOffset())}
+	}
+	if m.root == nil || m.root.name.Local != "assembly" {
+		return nil, errors.New(errInvalidManifest)
+	}
+
+	return m, nil
+}
+
+// NewManifest makes a Manifest document from an AppManifest.
+func NewManifest(manifest AppManifest) *Manifest {
+	m, err := ParseManifest(makeManifest(manifest))
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Bytes returns the xml document.
+func (m *Manifest) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	if m.bom {
+		buf.WriteString("\xEF\xBB\xBF")
+	}
+	for _, c := range m.doc.children {
+		writeXMLToken(buf, c)
+	}
+	return buf.Bytes()
+}
+
+// AppManifest returns the settings of the document, as AppManifestFromXML would.
+func (m *Manifest) AppManifest() AppManifest {
+	a, _ := AppManifestFromXML(m.Bytes())
+	return a
+}
+
+// Identity returns the assembly identity of the application.
+func (m *Manifest) Identity() AssemblyIdentity {
+	e := m.root.child("assemblyIdentity")
+	if e == nil {
+		return AssemblyIdentity{}
+	}
+	name, _ := e.attrValue("name")
+	version, _ := e.attrValue("version")
+	return AssemblyIdentity{Name: name, Version: readAssemblyVersion(version)}
+}
+
+// SetIdentity sets the assembly identity of the application.
+//
+// If the Name field is empty, the <assemblyIdentity> element is removed.
+func (m *Manifest) SetIdentity(identity AssemblyIdentity) {
+	e := m.root.child("assemblyIdentity")
+	if identity.Name == "" {
+		m.root.remove(e)
+		return
+	}
+	if e == nil {
+		// assemblyIdentity must be the first element
+		e = m.root.insertBefore(newXMLElement("assemblyIdentity"), m.root.firstElement(), nsAsmV1)
+		e.setAttr("type", "win32")
+	}
+	e.setAttr("name", identity.Name)
+	e.setAttr("version", assemblyVersionString(identity.Version))
+	if _, ok := e.attrValue("processorArchitecture"); !ok {
+		e.setAttr("processorArchitecture", "*")
+	}
+}
+
+// ExecutionLevel returns the requested execution level.
+func (m *Manifest) ExecutionLevel() ExecutionLevel {
+	return m.AppManifest().ExecutionLevel
+}
+
+// SetExecutionLevel sets the requested execution level.
+func (m *Manifest) SetExecutionLevel(level ExecutionLevel) {
+	var s string
+	switch level {
+	case RequireAdministrator:
+		s = "requireAdministrator"
+	case HighestAvailable:
+		s = "highestAvailable"
+	default:
+		s = "asInvoker"
+	}
+	e := m.requestedExecutionLevel()
+	e.setAttr("level", s)
+	if _, ok := e.attrValue("uiAccess"); !ok {
+		e.setAttr("uiAccess", "false")
+	}
+}
+
+// UIAccess returns true if the application requires access to other applications' UI elements.
+func (m *Manifest) UIAccess() bool {
+	return m.AppManifest().UIAccess
+}
+
+// SetUIAccess sets the uiAccess attribute of the requested execution level.
+func (m *Manifest) SetUIAccess(uiAccess bool) {
+	e := m.requestedExecutionLevel()
+	if _, ok := e.attrValue("level"); !ok {
+		e.setAttr("level", "asInvoker")
+	}
+	if uiAccess {
+		e.setAttr("uiAccess", "true")
+	} else {
+		e.setAttr("uiAccess", "false")
+	}
+}
+
+func (m *Manifest) requestedExecutionLevel() *xmlElement {
+	return m.root.
+		ensureChild("trustInfo", nsAsmV3).
+		ensureChild("security", "").
+		ensureChild("requestedPrivileges", "").
+		ensureChild("requestedExecutionLevel", "")
+}
+
+// Compatibility returns the minimum OS declared in the compatibility section.
+func (m *Manifest) Compatibility() SupportedOS {
+	return m.AppManifest().Compatibility
+}
+
+// SetCompatibility replaces the list of supported OS, keeping other elements such as <maxversiontested>.
+func (m *Manifest) SetCompatibility(os SupportedOS) error {
+	if os < WinVistaAndAbove || os > Win10AndAbove {
+		return errors.New(errUnknownSupportedOS)
+	}
+	app := m.root.ensureChild("compatibility", nsCompatibility).ensureChild("application", "")
+	for e := app.child("supportedOS"); e != nil; e = app.child("supportedOS") {
+		app.remove(e)
+	}
+	ids := []string{osWin10, osWin81, osWin8, osWin7, osWinVista}
+	for _, id := range ids[:int(Win10AndAbove-os)+1] {
+		app.append(newXMLElement("supportedOS"), "").setAttr("Id", id)
+	}
+	return nil
+}
+
+// DPIAwareness returns the DPI awareness of the application.
+func (m *Manifest) DPIAwareness() DPIAwareness {
+	return m.AppManifest().DPIAwareness
+}
+
+// SetDPIAwareness sets the <dpiAware> and <dpiAwareness> elements.
+//
+// It also sets <gdiScaling> for GDI scaled variants, and removes it for the others.
+func (m *Manifest) SetDPIAwareness(a DPIAwareness) error {
+	var dpiAware, dpiAwareness string
+	gdiScaling := ""
+	switch a {
+	case DPIAware:
+		dpiAware, dpiAwareness = "true", "system"
+	case DPIPerMonitor:
+		dpiAware, dpiAwareness = "true/pm", "permonitor"
+	case DPIPerMonitorV2:
+		dpiAware, dpiAwareness = "true", "permonitorv2,system"
+	case DPIUnaware:
+		dpiAware, dpiAwareness = "false", "unaware"
+	case DPIUnawareGDIScaled:
+		dpiAware, dpiAwareness, gdiScaling = "false", "unaware", "true"
+	case DPIPerMonitorV2GDIScaled:
+		dpiAware, dpiAwareness, gdiScaling = "false", "permonitorv2,unaware", "true"
+	default:
+		return errors.New(errUnknownDPIAwareness)
+	}
+	m.SetWindowsSetting("dpiAware", dpiAware)
+	m.SetWindowsSetting("dpiAwareness", dpiAwareness)
+	if gdiScaling != "" {
+		m.SetWindowsSetting("gdiScaling", gdiScaling)
+	} else {
+		m.RemoveWindowsSetting("gdiScaling")
+	}
+	return nil
+}
+
+// WindowsSetting returns the text of an element of <windowsSettings>, such as "longPathAware".
+//
+// The second return value is false if the element does not exist.
+func (m *Manifest) WindowsSetting(name string) (string, bool) {
+	e := m.root.child("application").child("windowsSettings").child(name)
+	if e == nil {
+		return "", false
+	}
+	return strings.TrimSpace(e.text()), true
+}
+
+// SetWindowsSetting sets the text of an element of <windowsSettings>, such as "longPathAware".
+//
+// If the element does not exist, it is created in its usual namespace,
+// so name must be one of the elements known by winres.
+func (m *Manifest) SetWindowsSetting(name string, value string) error {
+	e := m.root.child("application").child("windowsSettings").child(name)
+	if e == nil {
+		ns, ok := windowsSettingsNamespaces[name]
+		if !ok {
+			return errors.New(errUnknownWindowsSetting)
+		}
+		e = m.root.
+			ensureChild("application", nsAsmV3).
+			ensureChild("windowsSettings", "").
+			append(newXMLElement(name), ns)
+	}
+	e.setText(value)
+	return nil
+}
+
+// RemoveWindowsSetting removes an element of <windowsSettings>, if it exists.
+func (m *Manifest) RemoveWindowsSetting(name string) {
+	settings := m.root.child("application").child("windowsSettings")
+	settings.remove(settings.child(name))
+}
+
+func newXMLElement(local string) *xmlElement {
+	return &xmlElement{name: xml.Name{Local: local}}
+}
+
+// child returns the first child element with this local name, or nil.
+//
+// It accepts a nil receiver, so that calls can be chained.
+func (e *xmlElement) child(local string) *xmlElement {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.children {
+		if c, ok := c.(*xmlElement); ok && c.name.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+func (e *xmlElement) firstElement() *xmlElement {
+	for _, c := range e.children {
+		if c, ok := c.(*xmlElement); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// ensureChild returns the first child element with this local name, creating it if needed.
+func (e *xmlElement) ensureChild(local string, ns string) *xmlElement {
+	if c := e.child(local); c != nil {
+		return c
+	}
+	return e.append(newXMLElement(local), ns)
+}
+
+// append adds a child element after the last one.
+//
+// If ns is empty, the new element belongs to the namespace of e, and gets the same prefix.
+// Otherwise, the prefix of e is reused if it is bound to ns,
+// and ns is declared on the new element if it differs from the default namespace in scope.
+func (e *xmlElement) append(c *xmlElement, ns string) *xmlElement {
+	return e.insertBefore(c, nil, ns)
+}
+
+// insertBefore inserts a child element before ref, or after the last child if ref is nil.
+//
+// The namespace of the new element is set as in append. Indentation is copied from siblings.
+func (e *xmlElement) insertBefore(c *xmlElement, ref *xmlElement, ns string) *xmlElement {
+	switch {
+	case ns == "" || e.name.Space != "" && e.lookupNamespace(e.name.Space) == ns:
+		c.name.Space = e.name.Space
+	case e.lookupNamespace("") != ns:
+		c.attr = append([]xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: ns}}, c.attr...)
+	}
+	c.parent = e
+
+	indent := e.childIndent()
+	i := len(e.children)
+	if ref != nil {
+		i = e.indexOf(ref)
+		// Keep ref's indentation for the new element, and indent ref again
+		e.children = insertTokens(e.children, i, c, xml.CharData(indent))
+		return c
+	}
+	if i > 0 && isBlank(e.children[i-1]) {
+		// Insert before the closing tag's indentation
+		e.children = insertTokens(e.children, i-1, xml.CharData(indent), c)
+		return c
+	}
+	if e.parent != nil {
+		e.children = append(e.children, xml.CharData(indent), c, xml.CharData("\n"+e.indent()))
+	} else {
+		e.children = append(e.children, c)
+	}
+	return c
+}
+
+// remove removes a child element and its indentation. It does nothing if e or c is nil.
+func (e *xmlElement) remove(c *xmlElement) {
+	if e == nil || c == nil {
+		return
+	}
+	i := e.indexOf(c)
+	if i < 0 {
+		return
+	}
+	if i > 0 && isBlank(e.children[i-1]) {
+		e.children = append(e.children[:i-1], e.children[i+1:]...)
+		return
+	}
+	e.children = append(e.children[:i], e.children[i+1:]...)
+}
+
+func (e *xmlElement) indexOf(c *xmlElement) int {
+	for i := range e.children {
+		if e.children[i] == xml.Token(c) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indent returns the whitespace preceding the element on its line.
+func (e *xmlElement) indent() string {
+	if e.parent == nil {
+		return ""
+	}
+	i := e.parent.indexOf(e)
+	if i <= 0 || !isBlank(e.parent.children[i-1]) {
+		return ""
+	}
+	s := string(e.parent.children[i-1].(xml.CharData))
+	return s[strings.LastIndexByte(s, '\n')+1:]
+}
+
+// childIndent returns the whitespace that should precede a new child element.
+func (e *xmlElement) childIndent() string {
+	for i, c := range e.children {
+		if _, ok := c.(*xmlElement); ok && i > 0 && isBlank(e.children[i-1]) {
+			s := string(e.children[i-1].(xml.CharData))
+			return s[strings.LastIndexByte(s, '\n'):]
+		}
+	}
+	return "\n" + e.indent() + "  "
+}
+
+// lookupNamespace returns the namespace bound to a prefix in the scope of the element.
+func (e *xmlElement) lookupNamespace(prefix string) string {
+	for ; e != nil; e = e.parent {
+		for _, a := range e.attr {
+			if prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns" ||
+				prefix != "" && a.Name.Space == "xmlns" && a.Name.Local == prefix {
+				return a.Value
+			}
+		}
+	}
+	return ""
+}
+
+func (e *xmlElement) attrValue(local string) (string, bool) {
+	for _, a := range e.attr {
+		if a.Name.Local == local && a.Name.Space != "xmlns" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e *xmlElement) setAttr(local string, value string) {
+	for i := range e.attr {
+		if e.attr[i].Name.Local == local && e.attr[i].Name.Space != "xmlns" {
+			e.attr[i].Value = value
+			return
+		}
+	}
+	e.attr = append(e.attr, xml.Attr{Name: xml.Name{Local: local}, Value: value})
+}
+
+func (e *xmlElement) text() string {
+	var s strings.Builder
+	for _, c := range e.children {
+		if c, ok := c.(xml.CharData); ok {
+			s.Write(c)
+		}
+	}
+	return s.String()
+}
+
+// setText replaces the content of the element with a text.
+func (e *xmlElement) setText(s string) {
+	e.children = []xml.Token{xml.CharData(s)}
+}
+
+func insertTokens(tokens []xml.Token, i int, t ...xml.Token) []xml.Token {
+	return append(tokens[:i], append(t, tokens[i:]...)...)
+}
+
+func isBlank(t xml.Token) bool {
+	c, ok := t.(xml.CharData)
+	return ok && len(bytes.TrimSpace(c)) == 0
+}
+
+func rawName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func lineOf(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte{'\n'}) + 1
+}
+
+func writeXMLToken(buf *bytes.Buffer, t xml.Token) {
+	switch t := t.(type) {
+	case *xmlElement:
+		buf.WriteString("<" + rawName(t.name))
+		for _, a := range t.attr {
+			buf.WriteString(" " + rawName(a.Name) + `="`)
+			escapeXML(buf, a.Value, true)
+			buf.WriteString(`"`)
+		}
+		if len(t.children) == 0 {
+			buf.WriteString("/>")
+			return
+		}
+		buf.WriteString(">")
+		for _, c := range t.children {
+			writeXMLToken(buf, c)
+		}
+		buf.WriteString("</" + rawName(t.name) + ">")
+	case xml.CharData:
+		escapeXML(buf, string(t), false)
+	case xml.Comment:
+		buf.WriteString("<!--")
+		buf.Write(t)
+		buf.WriteString("-->")
+	case xml.ProcInst:
+		buf.WriteString("<?" + t.Target)
+		if len(t.Inst) > 0 {
+			buf.WriteString(" ")
+			buf.Write(t.Inst)
+		}
+		buf.WriteString("?>")
+	case xml.Directive:
+		buf.WriteString("<!")
+		buf.Write(t)
+		buf.WriteString(">")
+	}
+}
+
+// escapeXML is like xml.EscapeText, except it does not escape white space in text, so that indentation is preserved.
+func escapeXML(buf *bytes.Buffer, s string, attr bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			buf.WriteString("&amp;")
+		case r == '<':
+			buf.WriteString("&lt;")
+		case r == '>':
+			buf.WriteString("&gt;")
+		case r == '"' && attr:
+			buf.WriteString("&quot;")
+		case r == '\n' && attr:
+			buf.WriteString("&#xA;")
+		case r == '\t' && attr:
+			buf.WriteString("&#x9;")
+		case r == '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
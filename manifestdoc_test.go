@@ -0,0 +1,307 @@
+package winres
+
+import (
+	"reflect"
+	"testing"
+)
+
+// language=manifest
+const testManifestDocument = "\xEF\xBB\xBF" + `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!-- Generated by hand -->
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <assemblyIdentity type="win32" name="App" version="1.2.3.4" processorArchitecture="*"/>
+  <unknown attr="&quot;value&quot; &amp; &lt;more&gt;">text &amp; <b>bold</b></unknown>
+  <asmv3:trustInfo>
+    <asmv3:security>
+      <asmv3:requestedPrivileges>
+        <asmv3:requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </asmv3:requestedPrivileges>
+    </asmv3:security>
+  </asmv3:trustInfo>
+  <asmv3:application>
+    <asmv3:windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">true</dpiAware>
+      <custom xmlns="urn:custom">42</custom>
+    </asmv3:windowsSettings>
+  </asmv3:application>
+</assembly>
+<!-- The end -->
+`
+
+func TestParseManifest_RoundTrip(t *testing.T) {
+	m, err := ParseManifest([]byte(testManifestDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Bytes()) != testManifestDocument {
+		t.Errorf("*** Bytes():\n%s###\n*** want:\n%s###", m.Bytes(), testManifestDocument)
+	}
+
+	if id := m.Identity(); id.Name != "App" || id.Version != [4]uint16{1, 2, 3, 4} {
+		t.Error(id)
+	}
+	if m.ExecutionLevel() != AsInvoker || m.UIAccess() || m.DPIAwareness() != DPIAware {
+		t.Fail()
+	}
+	if v, ok := m.WindowsSetting("custom"); !ok || v != "42" {
+		t.Error(v, ok)
+	}
+	if _, ok := m.WindowsSetting("longPathAware"); ok {
+		t.Fail()
+	}
+}
+
+func TestManifest_Set(t *testing.T) {
+	m, err := ParseManifest([]byte(testManifestDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.SetExecutionLevel(RequireAdministrator)
+	m.SetUIAccess(true)
+	if err = m.SetDPIAwareness(DPIPerMonitorV2GDIScaled); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.SetWindowsSetting("longPathAware", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.SetWindowsSetting("custom", "43"); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.SetCompatibility(Win81AndAbove); err != nil {
+		t.Fatal(err)
+	}
+	m.SetIdentity(AssemblyIdentity{Name: "New.App", Version: [4]uint16{5}})
+
+	// language=manifest
+	want := "\xEF\xBB\xBF" + `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!-- Generated by hand -->
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <assemblyIdentity type="win32" name="New.App" version="5.0.0.0" processorArchitecture="*"/>
+  <unknown attr="&quot;value&quot; &amp; &lt;more&gt;">text &amp; <b>bold</b></unknown>
+  <asmv3:trustInfo>
+    <asmv3:security>
+      <asmv3:requestedPrivileges>
+        <asmv3:requestedExecutionLevel level="requireAdministrator" uiAccess="true"/>
+      </asmv3:requestedPrivileges>
+    </asmv3:security>
+  </asmv3:trustInfo>
+  <asmv3:application>
+    <asmv3:windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">false</dpiAware>
+      <custom xmlns="urn:custom">43</custom>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">permonitorv2,unaware</dpiAwareness>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+      <longPathAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</longPathAware>
+    </asmv3:windowsSettings>
+  </asmv3:application>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+      <supportedOS Id="{1f676c76-80e1-4239-95bb-83d0f6d0da78}"/>
+    </application>
+  </compatibility>
+</assembly>
+<!-- The end -->
+`
+	if string(m.Bytes()) != want {
+		t.Errorf("*** Bytes():\n%s###\n*** want:\n%s###", m.Bytes(), want)
+	}
+
+	if m.ExecutionLevel() != RequireAdministrator || !m.UIAccess() || m.DPIAwareness() != DPIPerMonitorV2GDIScaled || m.Compatibility() != Win81AndAbove {
+		t.Fail()
+	}
+
+	if err = m.SetDPIAwareness(DPIUnaware); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.WindowsSetting("gdiScaling"); ok || m.DPIAwareness() != DPIUnaware {
+		t.Error("gdiScaling should have been removed")
+	}
+	m.SetIdentity(AssemblyIdentity{})
+	if m.Identity().Name != "" {
+		t.Fail()
+	}
+}
+
+func TestManifest_Set_Prefixed(t *testing.T) {
+	// language=manifest
+	m, err := ParseManifest([]byte(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <asmv3:trustInfo>
+  </asmv3:trustInfo>
+  <asmv3:application>
+  </asmv3:application>
+</assembly>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.SetWindowsSetting("longPathAware", "true"); err != nil {
+		t.Fatal(err)
+	}
+	m.SetExecutionLevel(RequireAdministrator)
+
+	// language=manifest
+	want := `<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <asmv3:trustInfo>
+    <asmv3:security>
+      <asmv3:requestedPrivileges>
+        <asmv3:requestedExecutionLevel level="requireAdministrator" uiAccess="false"/>
+      </asmv3:requestedPrivileges>
+    </asmv3:security>
+  </asmv3:trustInfo>
+  <asmv3:application>
+    <asmv3:windowsSettings>
+      <longPathAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</longPathAware>
+    </asmv3:windowsSettings>
+  </asmv3:application>
+</assembly>`
+	if string(m.Bytes()) != want {
+		t.Errorf("*** Bytes():\n%s###\n*** want:\n%s###", m.Bytes(), want)
+	}
+
+	am, err := AppManifestFromXML(m.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if am.ExecutionLevel != RequireAdministrator || !am.LongPathAware {
+		t.Errorf("%+v", am)
+	}
+}
+
+func TestManifest_Create(t *testing.T) {
+	// language=manifest
+	m, err := ParseManifest([]byte(`<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0"></assembly>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetUIAccess(true)
+	m.SetDPIAwareness(DPIUnawareGDIScaled)
+	m.SetCompatibility(Win10AndAbove)
+	m.SetIdentity(AssemblyIdentity{Name: "app"})
+
+	// language=manifest
+	want := `<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="app" version="0.0.0.0" processorArchitecture="*"/>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="true"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">false</dpiAware>
+      <dpiAwareness xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">unaware</dpiAwareness>
+      <gdiScaling xmlns="http://schemas.microsoft.com/SMI/2017/WindowsSettings">true</gdiScaling>
+    </windowsSettings>
+  </application>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </application>
+  </compatibility>
+</assembly>`
+	if string(m.Bytes()) != want {
+		t.Errorf("*** Bytes():\n%s###\n*** want:\n%s###", m.Bytes(), want)
+	}
+}
+
+func TestManifest_SetDPIAwareness_GDIScaling(t *testing.T) {
+	all := []DPIAwareness{DPIAware, DPIUnaware, DPIPerMonitor, DPIPerMonitorV2, DPIUnawareGDIScaled, DPIPerMonitorV2GDIScaled}
+	for _, from := range []DPIAwareness{DPIUnawareGDIScaled, DPIPerMonitorV2GDIScaled} {
+		for _, to := range all {
+			m := NewManifest(AppManifest{DPIAwareness: from})
+			if err := m.SetDPIAwareness(to); err != nil {
+				t.Fatal(err)
+			}
+			_, ok := m.WindowsSetting("gdiScaling")
+			if ok != (to == DPIUnawareGDIScaled || to == DPIPerMonitorV2GDIScaled) || m.DPIAwareness() != to {
+				t.Errorf("%v -> %v: gdiScaling %v, DPIAwareness %v", from, to, ok, m.DPIAwareness())
+			}
+		}
+	}
+}
+
+func TestNewManifest(t *testing.T) {
+	a := AppManifest{
+		Identity:       AssemblyIdentity{Name: "app", Version: [4]uint16{1, 2, 3, 4}},
+		ExecutionLevel: HighestAvailable,
+		DPIAwareness:   DPIPerMonitorV2,
+		LongPathAware:  true,
+	}
+	m := NewManifest(a)
+	if string(m.Bytes()) != string(makeManifest(a)) {
+		t.Error("NewManifest should keep the xml untouched")
+	}
+	if !reflect.DeepEqual(m.AppManifest(), a) {
+		t.Error(m.AppManifest())
+	}
+}
+
+func TestParseManifest_Err(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		wantErr string
+	}{
+		{name: "syntax", xml: `<assembly><a></b></assembly>`, wantErr: "XML syntax error on line 1: unexpected end element </b>"},
+		{name: "eof", xml: "<assembly>\n<a>", wantErr: "XML syntax error on line 2: unexpected EOF"},
+		{name: "decoder", xml: `<assembly a=></assembly>`, wantErr: "*"},
+		{name: "end", xml: `</assembly>`, wantErr: "XML syntax error on line 1: unexpected end element </assembly>"},
+		{name: "root", xml: `<application/>`, wantErr: errInvalidManifest},
+		{name: "empty", xml: ``, wantErr: errInvalidManifest},
+		{name: "two roots", xml: `<assembly/><assembly/>`, wantErr: errInvalidManifest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseManifest([]byte(tt.xml))
+			if m != nil || !isErr(err, tt.wantErr) {
+				t.Errorf("ParseManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifest_Set_Err(t *testing.T) {
+	m := NewManifest(AppManifest{})
+	if !isErr(m.SetWindowsSetting("unknownSetting", "true"), errUnknownWindowsSetting) {
+		t.Fail()
+	}
+	if !isErr(m.SetDPIAwareness(42), errUnknownDPIAwareness) {
+		t.Fail()
+	}
+	if !isErr(m.SetCompatibility(42), errUnknownSupportedOS) {
+		t.Fail()
+	}
+	if string(m.Bytes()) != string(makeManifest(AppManifest{})) {
+		t.Error("manifest should not have changed")
+	}
+}
+
+func TestResourceSet_ManifestDocument(t *testing.T) {
+	rs := ResourceSet{}
+	if _, err := rs.GetManifestDocument(); !isErr(err, errNoManifest) {
+		t.Fail()
+	}
+
+	rs.Set(RT_MANIFEST, ID(1), LCIDDefault, []byte(testManifestDocument))
+	m, err := rs.GetManifestDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetExecutionLevel(HighestAvailable)
+	rs.SetManifestDocument(m)
+
+	m, err = rs.GetManifestDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ExecutionLevel() != HighestAvailable {
+		t.Fail()
+	}
+	if v, _ := m.WindowsSetting("custom"); v != "42" {
+		t.Error("unknown settings should have been kept")
+	}
+}
@@ -0,0 +1,245 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ManifestError is a problem found by ValidateManifest, with its position in the xml document.
+type ManifestError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ManifestError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// manifestRepeatableElements are the elements that may appear more than once in a same parent.
+var manifestRepeatableElements = map[string]bool{
+	"supportedOS":                   true,
+	"maxversiontested":              true,
+	"dependency":                    true,
+	"file":                          true,
+	"comClass":                      true,
+	"typelib":                       true,
+	"windowClass":                   true,
+	"comInterfaceProxyStub":         true,
+	"comInterfaceExternalProxyStub": true,
+	"clrClass":                      true,
+	"clrSurrogate":                  true,
+	"progid":                        true,
+}
+
+// manifestNamespaces are the expected namespaces of structural elements, by parent and element name.
+var manifestNamespaces = map[[2]string]string{
+	{"assembly", "application"}:                        nsAsmV3,
+	{"application", "windowsSettings"}:                 nsAsmV3,
+	{"assembly", "trustInfo"}:                          nsAsmV3,
+	{"trustInfo", "security"}:                          nsAsmV3,
+	{"security", "requestedPrivileges"}:                nsAsmV3,
+	{"requestedPrivileges", "requestedExecutionLevel"}: nsAsmV3,
+	{"assembly", "compatibility"}:                      nsCompatibility,
+	{"compatibility", "application"}:                   nsCompatibility,
+	{"application", "supportedOS"}:                     nsCompatibility,
+	{"application", "maxversiontested"}:                nsCompatibility,
+}
+
+// ValidateManifest checks an xml manifest against the rules enforced by the side-by-side loader,
+// which would otherwise refuse to start the application with a rather cryptic message.
+//
+// It returns a list of errors, in the order they appear in the document.
+// An empty list means no problem was found.
+//
+// These are the checks:
+//   - the document must be well-formed xml, with an xml declaration for UTF-8 encoding
+//   - the root element must be <assembly>, in the asm.v1 namespace
+//   - <application>, <trustInfo> and their children must be in the asm.v3 namespace
+//   - <compatibility> and its children must be in the compatibility.v1 namespace
+//   - each element of <windowsSettings> must be in its own namespace
+//   - <assemblyIdentity> must have a name and a version made of four numbers
+//   - <requestedExecutionLevel> must have a valid level and uiAccess attribute
+//   - elements such as <trustInfo> or <dpiAware> must not be repeated
+func ValidateManifest(data []byte) []ManifestError {
+	type frame struct {
+		name xml.Name
+		seen map[xml.Name]bool
+	}
+
+	var (
+		errs   []ManifestError
+		stack  []*frame
+		offset int64
+	)
+
+	add := func(offset int64, format string, a ...interface{}) {
+		line, col := textPosition(data, offset)
+		errs = append(errs, ManifestError{Line: line, Column: col, Message: fmt.Sprintf(format, a...)})
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	// Other encodings are reported below, rather than failing to decode
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	declared, hasRoot := false, false
+	for {
+		offset = d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			msg := err.Error()
+			if err, ok := err.(*xml.SyntaxError); ok {
+				msg = err.Msg
+			}
+			add(d.InputOffset(), "%s", msg)
+			return errs
+		}
+
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			if t.Target != "xml" || declared || len(stack) > 0 {
+				continue
+			}
+			declared = true
+			if enc := procInstParam(string(t.Inst), "encoding"); !strings.EqualFold(enc, "UTF-8") {
+				add(offset, `encoding is %q, it should be "UTF-8"`, enc)
+			}
+
+		case xml.CharData:
+			if len(stack) == 0 && len(bytes.Trim(t, " \t\r\n\uFEFF")) > 0 {
+				add(offset, "unexpected text outside of <assembly>")
+			}
+
+		case xml.StartElement:
+			if len(stack) == 0 {
+				hasRoot = true
+				if !declared {
+					add(offset, `missing xml declaration: <?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+					declared = true
+				}
+				if t.Name.Local != "assembly" || t.Name.Space != nsAsmV1 {
+					add(offset, "root element must be <assembly> in namespace %q", nsAsmV1)
+				} else if v, _ := xmlAttr(t, "manifestVersion"); v != "1.0" {
+					add(offset, `manifestVersion is %q, it should be "1.0"`, v)
+				}
+			} else {
+				parent := stack[len(stack)-1]
+				if isMicrosoftNamespace(t.Name.Space) && !manifestRepeatableElements[t.Name.Local] {
+					if parent.seen[t.Name] {
+						add(offset, "duplicate <%s> element in <%s>", t.Name.Local, parent.name.Local)
+					}
+					parent.seen[t.Name] = true
+				}
+				ns, ok := manifestNamespaces[[2]string{parent.name.Local, t.Name.Local}]
+				if parent.name.Local == "windowsSettings" {
+					ns, ok = windowsSettingsNamespaces[t.Name.Local]
+				}
+				if ok && t.Name.Space != ns {
+					add(offset, "<%s> is in namespace %q, it should be %q", t.Name.Local, t.Name.Space, ns)
+				}
+			}
+
+			switch t.Name.Local {
+			case "assemblyIdentity":
+				if name, ok := xmlAttr(t, "name"); !ok || name == "" {
+					add(offset, "<assemblyIdentity> must have a name")
+				}
+				if version, ok := xmlAttr(t, "version"); !ok {
+					add(offset, "<assemblyIdentity> must have a version")
+				} else if !isValidAssemblyVersion(version) {
+					add(offset, "version %q must be made of four numbers between 0 and 65535, such as \"1.0.0.0\"", version)
+				}
+			case "requestedExecutionLevel":
+				switch level, _ := xmlAttr(t, "level"); level {
+				case "asInvoker", "highestAvailable", "requireAdministrator":
+				default:
+					add(offset, `level is %q, it should be "asInvoker", "highestAvailable" or "requireAdministrator"`, level)
+				}
+				if uiAccess, ok := xmlAttr(t, "uiAccess"); ok && uiAccess != "true" && uiAccess != "false" {
+					add(offset, `uiAccess is %q, it should be "true" or "false"`, uiAccess)
+				}
+			}
+
+			stack = append(stack, &frame{name: t.Name, seen: make(map[xml.Name]bool)})
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if !hasRoot {
+		add(int64(len(data)), "missing <assembly> element")
+	}
+
+	return errs
+}
+
+func isMicrosoftNamespace(ns string) bool {
+	return strings.HasPrefix(ns, "urn:schemas-microsoft-com:") || strings.HasPrefix(ns, "http://schemas.microsoft.com/SMI/")
+}
+
+func isValidAssemblyVersion(s string) bool {
+	v := strings.Split(s, ".")
+	if len(v) != 4 {
+		return false
+	}
+	for i := range v {
+		if v[i] == "" || strings.TrimLeft(v[i], "0123456789") != "" {
+			return false
+		}
+		if _, err := strconv.ParseUint(v[i], 10, 16); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func xmlAttr(se xml.StartElement, local string) (string, bool) {
+	for _, a := range se.Attr {
+		if a.Name.Space == "" && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// procInstParam returns the value of a parameter in an xml declaration, or an empty string.
+func procInstParam(inst string, param string) string {
+	i := strings.Index(inst, param)
+	if i < 0 {
+		return ""
+	}
+	s := strings.TrimLeft(inst[i+len(param):], " \t\r\n")
+	if !strings.HasPrefix(s, "=") {
+		return ""
+	}
+	s = strings.TrimLeft(s[1:], " \t\r\n")
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return ""
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return ""
+	}
+	return s[1 : end+1]
+}
+
+// textPosition returns the line and column of a byte offset.
+func textPosition(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return bytes.Count(before, []byte{'\n'}) + 1, utf8.RuneCount(before[lineStart:]) + 1
+}
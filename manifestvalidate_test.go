@@ -0,0 +1,186 @@
+package winres
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want []string
+	}{
+		{
+			name: "generated",
+			xml:  string(makeManifest(AppManifest{DPIAwareness: DPIPerMonitorV2GDIScaled, LongPathAware: true, ActiveCodePage: "UTF-8", UseCommonControlsV6: true})),
+		},
+		{
+			name: "generated identity",
+			xml: string(makeManifest(AppManifest{
+				Identity:     AssemblyIdentity{Name: "app", Version: [4]uint16{1, 2, 3, 65535}},
+				Dependencies: []AssemblyReference{{Name: "dep", Version: [4]uint16{1}}},
+				Files:        []ManifestFile{{Name: "a.dll", COMClasses: []COMClass{{CLSID: "{1}"}, {CLSID: "{2}"}}}},
+			})),
+		},
+		{
+			name: "syntax",
+			xml:  "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<assembly xmlns=\"urn:schemas-microsoft-com:asm.v1\" manifestVersion=\"1.0\">\n  <a></b>\n</assembly>",
+			want: []string{"line 3, column 10: element <a> closed by </b>"},
+		},
+		{
+			name: "declaration",
+			xml:  `<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0"/>`,
+			want: []string{`line 1, column 1: missing xml declaration: <?xml version="1.0" encoding="UTF-8" standalone="yes"?>`},
+		},
+		{
+			name: "encoding",
+			xml:  "<?xml version=\"1.0\" encoding='UTF-16'?>\n\n<assembly xmlns=\"urn:schemas-microsoft-com:asm.v1\" manifestVersion=\"1.0\"/>",
+			want: []string{`line 1, column 1: encoding is "UTF-16", it should be "UTF-8"`},
+		},
+		{
+			name: "no encoding",
+			xml:  "\uFEFF<?xml version=\"1.0\"?><assembly xmlns=\"urn:schemas-microsoft-com:asm.v1\" manifestVersion=\"1.0\"/>",
+			want: []string{`line 1, column 2: encoding is "", it should be "UTF-8"`},
+		},
+		{
+			name: "empty",
+			xml:  "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n",
+			want: []string{`line 2, column 1: missing <assembly> element`},
+		},
+		{
+			name: "root",
+			xml: `<?xml version="1.0" encoding="UTF-8"?>
+<assembly manifestVersion="1.0"/>
+text`,
+			want: []string{
+				`line 2, column 1: root element must be <assembly> in namespace "urn:schemas-microsoft-com:asm.v1"`,
+				`line 2, column 34: unexpected text outside of <assembly>`,
+			},
+		},
+		{
+			name: "manifestVersion",
+			xml: `<?xml version="1.0" encoding="UTF-8"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="2.0"/>`,
+			want: []string{`line 2, column 1: manifestVersion is "2.0", it should be "1.0"`},
+		},
+		{
+			name: "errors",
+			// language=manifest
+			xml: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="app" version="1.2.3"/>
+  <assemblyIdentity type="win32" version="1.2.3.65536"/>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings>
+      <dpiAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</dpiAware>
+      <dpiAwareness>system</dpiAwareness>
+      <custom xmlns="urn:custom">1</custom>
+      <custom xmlns="urn:custom">2</custom>
+      <longPathAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</longPathAware>
+      <longPathAware xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">true</longPathAware>
+    </windowsSettings>
+  </application>
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="AsInvoker" uiAccess="no"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="dep" version=" 1.0.0.0"/>
+    </dependentAssembly>
+  </dependency>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="dep2"/>
+    </dependentAssembly>
+  </dependency>
+</assembly>`,
+			want: []string{
+				`line 3, column 3: version "1.2.3" must be made of four numbers between 0 and 65535, such as "1.0.0.0"`,
+				`line 4, column 3: duplicate <assemblyIdentity> element in <assembly>`,
+				`line 4, column 3: <assemblyIdentity> must have a name`,
+				`line 4, column 3: version "1.2.3.65536" must be made of four numbers between 0 and 65535, such as "1.0.0.0"`,
+				`line 7, column 7: <dpiAware> is in namespace "http://schemas.microsoft.com/SMI/2016/WindowsSettings", it should be "http://schemas.microsoft.com/SMI/2005/WindowsSettings"`,
+				`line 8, column 7: <dpiAwareness> is in namespace "urn:schemas-microsoft-com:asm.v3", it should be "http://schemas.microsoft.com/SMI/2016/WindowsSettings"`,
+				`line 12, column 7: duplicate <longPathAware> element in <windowsSettings>`,
+				`line 18, column 9: level is "AsInvoker", it should be "asInvoker", "highestAvailable" or "requireAdministrator"`,
+				`line 18, column 9: uiAccess is "no", it should be "true" or "false"`,
+				`line 24, column 7: version " 1.0.0.0" must be made of four numbers between 0 and 65535, such as "1.0.0.0"`,
+				`line 29, column 7: <assemblyIdentity> must have a version`,
+			},
+		},
+		{
+			name: "namespaces",
+			// language=manifest
+			xml: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <application>
+    <asmv3:windowsSettings/>
+  </application>
+  <asmv3:trustInfo>
+    <security>
+      <asmv3:requestedPrivileges>
+        <asmv3:requestedExecutionLevel level="asInvoker"/>
+      </asmv3:requestedPrivileges>
+    </security>
+  </asmv3:trustInfo>
+  <asmv3:application>
+    <windowsSettings/>
+  </asmv3:application>
+  <compatibility xmlns="urn:schemas-microsoft-com:compatibility.v1">
+    <asmv3:application>
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}"/>
+    </asmv3:application>
+  </compatibility>
+  <compatibility>
+    <application xmlns="urn:schemas-microsoft-com:compatibility.v1">
+      <supportedOS Id="{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}" xmlns=""/>
+    </application>
+  </compatibility>
+</assembly>`,
+			want: []string{
+				`line 3, column 3: <application> is in namespace "urn:schemas-microsoft-com:asm.v1", it should be "urn:schemas-microsoft-com:asm.v3"`,
+				`line 7, column 5: <security> is in namespace "urn:schemas-microsoft-com:asm.v1", it should be "urn:schemas-microsoft-com:asm.v3"`,
+				`line 14, column 5: <windowsSettings> is in namespace "urn:schemas-microsoft-com:asm.v1", it should be "urn:schemas-microsoft-com:asm.v3"`,
+				`line 17, column 5: <application> is in namespace "urn:schemas-microsoft-com:asm.v3", it should be "urn:schemas-microsoft-com:compatibility.v1"`,
+				`line 21, column 3: <compatibility> is in namespace "urn:schemas-microsoft-com:asm.v1", it should be "urn:schemas-microsoft-com:compatibility.v1"`,
+				`line 23, column 7: <supportedOS> is in namespace "", it should be "urn:schemas-microsoft-com:compatibility.v1"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateManifest([]byte(tt.xml)) {
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateManifest() got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_procInstParam(t *testing.T) {
+	tests := []struct {
+		inst  string
+		param string
+		want  string
+	}{
+		{inst: `version="1.0" encoding="UTF-8"`, param: "encoding", want: "UTF-8"},
+		{inst: `version = '1.0'`, param: "version", want: "1.0"},
+		{inst: `version="1.0`, param: "version", want: ""},
+		{inst: `version=1.0`, param: "version", want: ""},
+		{inst: `version`, param: "version", want: ""},
+		{inst: `version="1.0"`, param: "encoding", want: ""},
+	}
+	for _, tt := range tests {
+		if got := procInstParam(tt.inst, tt.param); got != tt.want {
+			t.Errorf("procInstParam(%q, %q) = %q, want %q", tt.inst, tt.param, got, tt.want)
+		}
+	}
+}
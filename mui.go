@@ -0,0 +1,448 @@
+package winres
+
+// In this file are functions to split a multilingual resource set into a language-neutral file and .mui files.
+// https://docs.microsoft.com/en-us/windows/win32/intl/mui-resource-management
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/tc-hib/winres/lcid"
+)
+
+// RT_MUI is the type of the resource that links a language-neutral file to its .mui files.
+const RT_MUI = Name("MUI")
+
+// MUIFileType tells whether a MUI configuration belongs to a language-neutral file or to a .mui file.
+type MUIFileType uint32
+
+const (
+	MUIFileNeutral  MUIFileType = 0x11 // The main file, with language-neutral resources
+	MUIFileLanguage MUIFileType = 0x12 // A .mui file, with the resources of one language
+)
+
+// Where Windows finds the resources of the ultimate fallback language
+const (
+	MUIFallbackInternal = 1 // In the language-neutral file
+	MUIFallbackExternal = 2 // In the .mui file of the fallback language
+)
+
+// MUIConfig is the content of the RT_MUI resource.
+//
+// Windows only loads a .mui file if its checksum is the same as the language-neutral file's.
+type MUIConfig struct {
+	FileType         MUIFileType
+	SystemAttributes uint32
+	FallbackLocation uint32
+	ServiceChecksum  [16]byte
+	Checksum         [16]byte
+	MainTypes        []Identifier // Types of the resources in the language-neutral file
+	MUITypes         []Identifier // Types of the resources in .mui files
+	Language         string       // Language of a .mui file, such as "fr-FR"
+	FallbackLanguage string       // Ultimate fallback language, such as "en-US"
+}
+
+const (
+	muiConfigSignature    = 0xFECDFECD
+	muiConfigVersion      = 0x10000
+	sizeOfMUIConfigHeader = 0x84
+)
+
+type muiConfigHeader struct {
+	Signature              uint32
+	Size                   uint32
+	Version                uint32
+	Reserved               uint32
+	FileType               uint32
+	SystemAttributes       uint32
+	FallbackLocation       uint32
+	ServiceChecksum        [16]byte
+	Checksum               [16]byte
+	Reserved2              [24]byte
+	MainNameTypesOffset    uint32
+	MainNameTypesLength    uint32
+	MainIDTypesOffset      uint32
+	MainIDTypesLength      uint32
+	MUINameTypesOffset     uint32
+	MUINameTypesLength     uint32
+	MUIIDTypesOffset       uint32
+	MUIIDTypesLength       uint32
+	LanguageOffset         uint32
+	LanguageLength         uint32
+	FallbackLanguageOffset uint32
+	FallbackLanguageLength uint32
+}
+
+// MUIOptions are the options of SplitMUI.
+type MUIOptions struct {
+	// Types are the resource types to move to .mui files.
+	// When it is nil, every type moves, except icons, cursors and manifests.
+	// RT_VERSION is never moved, it is copied to every file.
+	Types []Identifier
+	// FallbackLanguage is the language Windows uses when no .mui file matches the user's languages.
+	// Its default value is LCIDDefault (en-US).
+	FallbackLanguage uint16
+}
+
+// MUIFiles is the result of SplitMUI.
+type MUIFiles struct {
+	// Neutral is the resource set of the language-neutral file, including its RT_MUI configuration.
+	Neutral *ResourceSet
+	// Languages are the resource sets of .mui files, by language ID.
+	// Each one should be written with WriteDLL, to the path MUIPath returns.
+	Languages map[uint16]*ResourceSet
+}
+
+// SplitMUI splits a multilingual resource set into a language-neutral resource set and one resource set per language.
+//
+// Translations of localizable types move to the .mui file of their language.
+// Neutral translations (LCIDNeutral) of those types are copied to every .mui file.
+// Other types stay in the language-neutral file, whatever their language.
+//
+// Every file gets an RT_MUI resource, with a checksum made from the resources of the fallback language.
+func (rs *ResourceSet) SplitMUI(opts MUIOptions) (*MUIFiles, error) {
+	fallback := opts.FallbackLanguage
+	if fallback == 0 {
+		fallback = LCIDDefault
+	}
+
+	isMUIType := func(typeID Identifier) bool {
+		switch typeID {
+		case RT_VERSION, RT_MUI:
+			return false
+		}
+		if opts.Types == nil {
+			switch typeID {
+			case RT_CURSOR, RT_ICON, RT_GROUP_CURSOR, RT_GROUP_ICON, RT_MANIFEST:
+				return false
+			}
+			return true
+		}
+		for _, t := range opts.Types {
+			if t == typeID {
+				return true
+			}
+		}
+		return false
+	}
+
+	files := &MUIFiles{
+		Neutral:   &ResourceSet{},
+		Languages: make(map[uint16]*ResourceSet),
+	}
+	type resource struct {
+		typeID, resID Identifier
+		data          []byte
+	}
+	var neutral []resource
+	mainTypes := map[Identifier]struct{}{}
+	muiTypes := map[Identifier]struct{}{}
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		switch {
+		case typeID == RT_MUI:
+		case !isMUIType(typeID):
+			files.Neutral.set(typeID, resID, langID, data)
+			mainTypes[typeID] = struct{}{}
+		case langID == LCIDNeutral:
+			neutral = append(neutral, resource{typeID, resID, data})
+			muiTypes[typeID] = struct{}{}
+		default:
+			if files.Languages[langID] == nil {
+				files.Languages[langID] = &ResourceSet{}
+			}
+			files.Languages[langID].set(typeID, resID, langID, data)
+			muiTypes[typeID] = struct{}{}
+		}
+		return true
+	})
+	if files.Languages[fallback] == nil {
+		return nil, errors.New(errMUIFallbackMissing)
+	}
+
+	for _, mui := range files.Languages {
+		for _, r := range neutral {
+			mui.set(r.typeID, r.resID, LCIDNeutral, r.data)
+		}
+	}
+	checksum := muiChecksum(files.Languages[fallback])
+
+	// Each .mui file must have its own VERSIONINFO
+	for langID, mui := range files.Languages {
+		rs.WalkType(RT_VERSION, func(resID Identifier, _ uint16, _ []byte) bool {
+			if mui.Get(RT_VERSION, resID, langID) == nil {
+				data, _ := rs.Find(RT_VERSION, resID, langID)
+				mui.set(RT_VERSION, resID, langID, data)
+			}
+			return true
+		})
+	}
+
+	config := MUIConfig{
+		FileType:         MUIFileNeutral,
+		FallbackLocation: MUIFallbackExternal,
+		ServiceChecksum:  checksum,
+		Checksum:         checksum,
+		MainTypes:        sortedIdentifiers(mainTypes),
+		MUITypes:         sortedIdentifiers(muiTypes),
+		FallbackLanguage: lcid.Tag(fallback),
+	}
+	if config.FallbackLanguage == "" {
+		return nil, errors.New(errInvalidLangID)
+	}
+	files.Neutral.set(RT_MUI, ID(1), LCIDNeutral, config.Bytes())
+
+	config.FileType = MUIFileLanguage
+	config.FallbackLanguage = ""
+	for langID, mui := range files.Languages {
+		config.Language = lcid.Tag(langID)
+		if config.Language == "" {
+			return nil, errors.New(errInvalidLangID)
+		}
+		mui.set(RT_MUI, ID(1), langID, config.Bytes())
+	}
+
+	return files, nil
+}
+
+// MUIPath returns the path of the .mui file of a language, which is in a directory named after the language.
+//
+// For example, the French .mui file of "dir/app.exe" is "dir/fr-FR/app.exe.mui".
+func MUIPath(path string, langID uint16) (string, error) {
+	tag := lcid.Tag(langID)
+	if tag == "" {
+		return "", errors.New(errInvalidLangID)
+	}
+	return filepath.Join(filepath.Dir(path), tag, filepath.Base(path)+".mui"), nil
+}
+
+// GetMUIConfig returns the MUI configuration of the resource set.
+func (rs *ResourceSet) GetMUIConfig() (*MUIConfig, error) {
+	var data []byte
+	rs.WalkType(RT_MUI, func(_ Identifier, _ uint16, d []byte) bool {
+		data = d
+		return false
+	})
+	if data == nil {
+		return nil, errors.New(errNoMUIConfig)
+	}
+	return ParseMUIConfig(data)
+}
+
+// ParseMUIConfig parses the content of an RT_MUI resource.
+func ParseMUIConfig(data []byte) (*MUIConfig, error) {
+	hdr := muiConfigHeader{}
+	if err := binaryRead(bytes.NewReader(data), &hdr); err != nil {
+		return nil, errors.New(errInvalidMUIConfig)
+	}
+	if hdr.Signature != muiConfigSignature || hdr.Size < sizeOfMUIConfigHeader || int(hdr.Size) > len(data) {
+		return nil, errors.New(errInvalidMUIConfig)
+	}
+	data = data[:hdr.Size]
+
+	section := func(offset, length uint32) ([]byte, bool) {
+		if length == 0 {
+			return nil, true
+		}
+		if offset < sizeOfMUIConfigHeader || uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, false
+		}
+		return data[offset : offset+length], true
+	}
+
+	c := &MUIConfig{
+		FileType:         MUIFileType(hdr.FileType),
+		SystemAttributes: hdr.SystemAttributes,
+		FallbackLocation: hdr.FallbackLocation,
+		ServiceChecksum:  hdr.ServiceChecksum,
+		Checksum:         hdr.Checksum,
+	}
+	var sections [6][]byte
+	for i, s := range [][2]uint32{
+		{hdr.MainNameTypesOffset, hdr.MainNameTypesLength},
+		{hdr.MainIDTypesOffset, hdr.MainIDTypesLength},
+		{hdr.MUINameTypesOffset, hdr.MUINameTypesLength},
+		{hdr.MUIIDTypesOffset, hdr.MUIIDTypesLength},
+		{hdr.LanguageOffset, hdr.LanguageLength},
+		{hdr.FallbackLanguageOffset, hdr.FallbackLanguageLength},
+	} {
+		var ok bool
+		if sections[i], ok = section(s[0], s[1]); !ok {
+			return nil, errors.New(errInvalidMUIConfig)
+		}
+	}
+
+	c.MainTypes = append(muiReadIDs(sections[1]), muiReadStrings(sections[0])...)
+	c.MUITypes = append(muiReadIDs(sections[3]), muiReadStrings(sections[2])...)
+	if s := muiReadStrings(sections[4]); len(s) > 0 {
+		c.Language = string(s[0].(Name))
+	}
+	if s := muiReadStrings(sections[5]); len(s) > 0 {
+		c.FallbackLanguage = string(s[0].(Name))
+	}
+	sortIdentifiers(c.MainTypes)
+	sortIdentifiers(c.MUITypes)
+
+	return c, nil
+}
+
+// Bytes returns the binary representation of the MUI configuration, which is the content of the RT_MUI resource.
+func (c *MUIConfig) Bytes() []byte {
+	hdr := muiConfigHeader{
+		Signature:        muiConfigSignature,
+		Version:          muiConfigVersion,
+		FileType:         uint32(c.FileType),
+		SystemAttributes: c.SystemAttributes,
+		FallbackLocation: c.FallbackLocation,
+		ServiceChecksum:  c.ServiceChecksum,
+		Checksum:         c.Checksum,
+	}
+
+	body := &bytes.Buffer{}
+	add := func(data []byte, offset, length *uint32) {
+		if len(data) == 0 {
+			return
+		}
+		for (sizeOfMUIConfigHeader+body.Len())%8 != 0 {
+			body.WriteByte(0)
+		}
+		*offset = uint32(sizeOfMUIConfigHeader + body.Len())
+		*length = uint32(len(data))
+		body.Write(data)
+	}
+
+	var mainNames, mainIDs, muiNames, muiIDs []Identifier
+	for _, t := range c.MainTypes {
+		if _, ok := t.(Name); ok {
+			mainNames = append(mainNames, t)
+		} else {
+			mainIDs = append(mainIDs, t)
+		}
+	}
+	for _, t := range c.MUITypes {
+		if _, ok := t.(Name); ok {
+			muiNames = append(muiNames, t)
+		} else {
+			muiIDs = append(muiIDs, t)
+		}
+	}
+	add(muiStringsBytes(mainNames), &hdr.MainNameTypesOffset, &hdr.MainNameTypesLength)
+	add(muiIDsBytes(mainIDs), &hdr.MainIDTypesOffset, &hdr.MainIDTypesLength)
+	add(muiStringsBytes(muiNames), &hdr.MUINameTypesOffset, &hdr.MUINameTypesLength)
+	add(muiIDsBytes(muiIDs), &hdr.MUIIDTypesOffset, &hdr.MUIIDTypesLength)
+	if c.Language != "" {
+		add(utf16Bytes(c.Language), &hdr.LanguageOffset, &hdr.LanguageLength)
+	}
+	if c.FallbackLanguage != "" {
+		add(utf16Bytes(c.FallbackLanguage), &hdr.FallbackLanguageOffset, &hdr.FallbackLanguageLength)
+	}
+	for (sizeOfMUIConfigHeader+body.Len())%8 != 0 {
+		body.WriteByte(0)
+	}
+	hdr.Size = uint32(sizeOfMUIConfigHeader + body.Len())
+
+	buf := bytes.NewBuffer(make([]byte, 0, hdr.Size))
+	binary.Write(buf, binary.LittleEndian, &hdr)
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// muiChecksum is the MD5 hash of every localizable resource of a .mui file.
+//
+// Windows doesn't care how it's computed, as long as it's the same in every file.
+func muiChecksum(rs *ResourceSet) [16]byte {
+	h := md5.New()
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		if typeID == RT_VERSION {
+			return true
+		}
+		binary.Write(h, binary.LittleEndian, []uint32{uint32(len(data)), uint32(langID)})
+		h.Write([]byte(identToConfig(typeID, true) + "\x00" + identToConfig(resID, false) + "\x00"))
+		h.Write(data)
+		return true
+	})
+	var sum [16]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// utf16Bytes returns a NUL terminated UTF-16 string.
+func utf16Bytes(s string) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, utf16.Encode([]rune(s+"\x00")))
+	return buf.Bytes()
+}
+
+// muiStringsBytes returns a list of NUL terminated UTF-16 names, terminated by an empty name.
+func muiStringsBytes(names []Identifier) []byte {
+	if len(names) == 0 {
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	for _, n := range names {
+		buf.Write(utf16Bytes(string(n.(Name))))
+	}
+	buf.Write([]byte{0, 0})
+	return buf.Bytes()
+}
+
+func muiIDsBytes(ids []Identifier) []byte {
+	buf := &bytes.Buffer{}
+	for _, id := range ids {
+		binary.Write(buf, binary.LittleEndian, uint32(id.(ID)))
+	}
+	return buf.Bytes()
+}
+
+func muiReadStrings(data []byte) []Identifier {
+	var (
+		names []Identifier
+		name  []uint16
+	)
+	for i := 0; i+1 < len(data); i += 2 {
+		c := uint16(data[i+1])<<8 | uint16(data[i])
+		if c != 0 {
+			name = append(name, c)
+			continue
+		}
+		if len(name) == 0 {
+			break
+		}
+		names = append(names, Name(utf16.Decode(name)))
+		name = nil
+	}
+	return names
+}
+
+func muiReadIDs(data []byte) []Identifier {
+	var ids []Identifier
+	for i := 0; i+4 <= len(data); i += 4 {
+		ids = append(ids, ID(binary.LittleEndian.Uint32(data[i:])))
+	}
+	return ids
+}
+
+func sortedIdentifiers(m map[Identifier]struct{}) []Identifier {
+	idents := make([]Identifier, 0, len(m))
+	for ident := range m {
+		idents = append(idents, ident)
+	}
+	sortIdentifiers(idents)
+	return idents
+}
+
+// sortIdentifiers sorts IDs by value, then names in alphabetical order.
+func sortIdentifiers(idents []Identifier) {
+	sort.SliceStable(idents, func(i, j int) bool {
+		_, iName := idents[i].(Name)
+		_, jName := idents[j].(Name)
+		if iName != jName {
+			return jName
+		}
+		return idents[i].lessThan(idents[j])
+	})
+}
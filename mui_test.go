@@ -0,0 +1,185 @@
+package winres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres/version"
+)
+
+func TestMUIConfig_Bytes(t *testing.T) {
+	if binary.Size(muiConfigHeader{}) != sizeOfMUIConfigHeader {
+		t.Fatal("wrong header size")
+	}
+
+	c := &MUIConfig{
+		FileType:         MUIFileLanguage,
+		SystemAttributes: 0x100,
+		FallbackLocation: MUIFallbackExternal,
+		ServiceChecksum:  [16]byte{1, 2, 3},
+		Checksum:         [16]byte{4, 5, 6},
+		MainTypes:        []Identifier{RT_ICON, RT_GROUP_ICON, RT_VERSION, RT_MANIFEST, Name("MUI")},
+		MUITypes:         []Identifier{RT_STRING, Name("CUSTOM"), Name("HTML")},
+		Language:         "fr-FR",
+	}
+	b := c.Bytes()
+	if len(b)%8 != 0 || binary.LittleEndian.Uint32(b[4:]) != uint32(len(b)) {
+		t.Error("size should be a multiple of 8")
+	}
+
+	got, err := ParseMUIConfig(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", c, got)
+	}
+	if !bytes.Equal(got.Bytes(), b) {
+		t.Fail()
+	}
+
+	empty := &MUIConfig{FileType: MUIFileNeutral}
+	got, err = ParseMUIConfig(empty.Bytes())
+	if err != nil || !reflect.DeepEqual(got, empty) || len(empty.Bytes()) != 136 {
+		t.Fail()
+	}
+}
+
+func TestParseMUIConfig_Err(t *testing.T) {
+	valid := (&MUIConfig{FallbackLanguage: "en-US", MainTypes: []Identifier{RT_ICON}}).Bytes()
+	corrupt := func(offset int, value uint32) []byte {
+		b := append([]byte{}, valid...)
+		binary.LittleEndian.PutUint32(b[offset:], value)
+		return b
+	}
+
+	for i, data := range [][]byte{
+		nil,
+		valid[:sizeOfMUIConfigHeader-1],
+		corrupt(0, 0xFECDFECE),
+		corrupt(4, uint32(len(valid)+1)),
+		corrupt(4, sizeOfMUIConfigHeader-1),
+		corrupt(0x5C, 0x10),
+		corrupt(0x60, uint32(len(valid))),
+		corrupt(0x7C, 0xFFFFFFFF),
+	} {
+		if c, err := ParseMUIConfig(data); c != nil || !isErr(err, errInvalidMUIConfig) {
+			t.Errorf("%d: %v", i, err)
+		}
+	}
+}
+
+func TestResourceSet_SplitMUI(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIcon(ID(1), newTestIcon(t, 32, 16))
+	rs.SetManifest(AppManifest{})
+	vi := version.Info{}
+	vi.Set(0x409, version.ProductName, "Product")
+	vi.Set(0x40C, version.ProductName, "Produit")
+	rs.SetVersionInfo(vi)
+	rs.Set(RT_STRING, ID(1), 0x409, []byte("en"))
+	rs.Set(RT_STRING, ID(1), 0x40C, []byte("fr"))
+	rs.Set(RT_STRING, ID(2), 0x411, []byte("ja"))
+	rs.Set(RT_RCDATA, ID(1), LCIDNeutral, []byte("neutral"))
+	rs.Set(Name("CUSTOM"), ID(1), 0x409, []byte("custom"))
+
+	files, err := rs.SplitMUI(MUIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln := files.Neutral
+	if ln.Get(RT_STRING, ID(1), 0x409) != nil || ln.Get(RT_RCDATA, ID(1), 0) != nil ||
+		ln.Get(RT_GROUP_ICON, ID(1), 0) == nil || ln.Get(RT_MANIFEST, ID(1), 0x409) == nil ||
+		ln.Get(RT_VERSION, ID(1), 0x40C) == nil {
+		t.Error("wrong language-neutral set")
+	}
+	config, err := ln.GetMUIConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.FileType != MUIFileNeutral || config.FallbackLanguage != "en-US" || config.Language != "" ||
+		config.FallbackLocation != MUIFallbackExternal ||
+		!reflect.DeepEqual(config.MainTypes, []Identifier{RT_ICON, RT_GROUP_ICON, RT_VERSION, RT_MANIFEST}) ||
+		!reflect.DeepEqual(config.MUITypes, []Identifier{RT_STRING, RT_RCDATA, Name("CUSTOM")}) {
+		t.Errorf("%+v", config)
+	}
+
+	if len(files.Languages) != 3 {
+		t.Fatal(len(files.Languages))
+	}
+	for langID, tag := range map[uint16]string{0x409: "en-US", 0x40C: "fr-FR", 0x411: "ja-JP"} {
+		mui := files.Languages[langID]
+		c, err := mui.GetMUIConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.FileType != MUIFileLanguage || c.Language != tag || c.FallbackLanguage != "" || c.Checksum != config.Checksum ||
+			!reflect.DeepEqual(c.MUITypes, config.MUITypes) {
+			t.Errorf("%04X: %+v", langID, c)
+		}
+		if string(mui.Get(RT_RCDATA, ID(1), 0)) != "neutral" || mui.Get(RT_GROUP_ICON, ID(1), 0) != nil {
+			t.Errorf("%04X: wrong resources", langID)
+		}
+		data, _ := rs.Find(RT_VERSION, ID(1), langID)
+		if !bytes.Equal(mui.Get(RT_VERSION, ID(1), langID), data) {
+			t.Errorf("%04X: wrong version info", langID)
+		}
+	}
+	if string(files.Languages[0x40C].Get(RT_STRING, ID(1), 0x40C)) != "fr" ||
+		files.Languages[0x40C].Get(Name("CUSTOM"), ID(1), 0x409) != nil ||
+		files.Languages[0x40C].Count() != 4 {
+		t.Error("wrong resources in fr-FR")
+	}
+
+	// Explicit types and fallback
+	files, err = rs.SplitMUI(MUIOptions{Types: []Identifier{RT_STRING, RT_VERSION}, FallbackLanguage: 0x40C})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _ = files.Neutral.GetMUIConfig()
+	if config.FallbackLanguage != "fr-FR" || !reflect.DeepEqual(config.MUITypes, []Identifier{RT_STRING}) ||
+		files.Neutral.Get(RT_RCDATA, ID(1), 0) == nil {
+		t.Errorf("%+v", config)
+	}
+	c409, _ := files.Languages[0x409].GetMUIConfig()
+	c411, _ := files.Languages[0x411].GetMUIConfig()
+	if c409.Checksum != config.Checksum || c411.Checksum != config.Checksum {
+		t.Fail()
+	}
+	files2, _ := rs.SplitMUI(MUIOptions{Types: []Identifier{RT_STRING}, FallbackLanguage: 0x409})
+	if c, _ := files2.Neutral.GetMUIConfig(); c.Checksum == config.Checksum {
+		t.Error("checksum should depend on the fallback language")
+	}
+}
+
+func TestResourceSet_SplitMUI_Err(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.Set(RT_STRING, ID(1), 0x40C, []byte("fr"))
+	if _, err := rs.SplitMUI(MUIOptions{}); !isErr(err, errMUIFallbackMissing) {
+		t.Error(err)
+	}
+	rs.Set(RT_STRING, ID(1), 0x0C00, []byte("?"))
+	if _, err := rs.SplitMUI(MUIOptions{FallbackLanguage: 0x40C}); !isErr(err, errInvalidLangID) {
+		t.Error(err)
+	}
+	if _, err := rs.SplitMUI(MUIOptions{FallbackLanguage: 0x0C00}); !isErr(err, errInvalidLangID) {
+		t.Error(err)
+	}
+	if _, err := rs.GetMUIConfig(); !isErr(err, errNoMUIConfig) {
+		t.Error(err)
+	}
+}
+
+func TestMUIPath(t *testing.T) {
+	p, err := MUIPath(filepath.Join("dir", "app.exe"), 0x40C)
+	if err != nil || p != filepath.Join("dir", "fr-FR", "app.exe.mui") {
+		t.Error(p, err)
+	}
+	if _, err = MUIPath("app.exe", 0x0C00); !isErr(err, errInvalidLangID) {
+		t.Error(err)
+	}
+}
@@ -13,6 +13,8 @@ const (
 	// Visual C++ pads resource data to 8 bytes.
 	// 4 bytes is a minimum.
 	dataAlignment = 8
+	// Sizes in the resource directory are 32-bit, and we also want padded sizes to fit an int on 32-bit platforms.
+	maxDataSize = 1<<31 - dataAlignment
 )
 
 // state is a temporary state used during the execution of ResourceSet.write()
@@ -29,6 +31,19 @@ type state struct {
 // It returns a slice of addresses for the relocation table.
 // https://docs.microsoft.com/en-us/previous-versions/ms809762(v=msdn.10)#pe-file-resources
 func (rs *ResourceSet) write(w io.Writer) ([]int, error) {
+	s, err := rs.writeDirectory(w)
+	if err != nil {
+		return nil, err
+	}
+	if err := rs.writeData(w, s); err != nil {
+		return nil, err
+	}
+	return append([]int{}, s.relocAddr...), nil
+}
+
+// writeDirectory writes the beginning of the rsrc section's content: everything but the actual data.
+// The returned state may then be passed to writeData.
+func (rs *ResourceSet) writeDirectory(w io.Writer) (*state, error) {
 	s := rs.prepare()
 	if err := rs.writeTypeDir(w, s); err != nil {
 		return nil, err
@@ -47,10 +62,7 @@ func (rs *ResourceSet) write(w io.Writer) ([]int, error) {
 	if err := binary.Write(w, binary.LittleEndian, s.namesData); err != nil {
 		return nil, err
 	}
-	if err := rs.writeData(w, s); err != nil {
-		return nil, err
-	}
-	return append([]int{}, s.relocAddr...), nil
+	return s, nil
 }
 
 // order orders identifiers in the whole resource set.
@@ -330,21 +342,77 @@ func (re *ResourceEntry) write(w io.Writer, s *state) error {
 	return nil
 }
 
+// DataEntry holds the data of a resource, in a given language.
+//
+// Data is nil when the resource was set with SetReader, or loaded with LoadFromEXELazy.
+// In this case, the actual data is read from its source when needed.
 type DataEntry struct {
 	Data []byte
+
+	size int64                         // size of the data, when Data is nil
+	open func() (io.ReadCloser, error) // source of the data, when Data is nil
 }
 
 func alignData(offset int) int {
 	return (offset + dataAlignment - 1) &^ (dataAlignment - 1)
 }
 
+// dataSize returns the size of the data, without reading it.
+func (de *DataEntry) dataSize() int {
+	if de.open != nil {
+		return int(de.size)
+	}
+	return len(de.Data)
+}
+
+// bytes returns the data, reading it from its source if necessary.
+//
+// It returns nil if the data could not be read.
+func (de *DataEntry) bytes() []byte {
+	data, _ := de.readData()
+	return data
+}
+
+// readData returns the data, reading it from its source if necessary.
+func (de *DataEntry) readData() ([]byte, error) {
+	if de.open == nil {
+		return de.Data, nil
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, de.size))
+	if err := de.copyData(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copyData copies data from its source into w, and checks its size.
+func (de *DataEntry) copyData(w io.Writer) error {
+	r, err := de.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if _, err = io.CopyN(w, r, de.size); err != nil {
+		if err == io.EOF {
+			return errors.New(errDataSizeMismatch)
+		}
+		return err
+	}
+	var b [1]byte
+	if n, _ := r.Read(b[:]); n > 0 {
+		return errors.New(errDataSizeMismatch)
+	}
+	return nil
+}
+
 // paddedDataSize returns the room taken by data, including some padding at the end.
 func (de *DataEntry) paddedDataSize() int {
-	return alignData(len(de.Data))
+	return alignData(de.dataSize())
 }
 
 func (de *DataEntry) write(w io.Writer, s *state) error {
-	if err := writeDataEntry(w, s.offset, len(de.Data)); err != nil {
+	if err := writeDataEntry(w, s.offset, de.dataSize()); err != nil {
 		return err
 	}
 	// Everything must be aligned, so we may skip a few byte when necessary after each resource data
@@ -353,13 +421,17 @@ func (de *DataEntry) write(w io.Writer, s *state) error {
 }
 
 func (de *DataEntry) writeData(w io.Writer) error {
-	size, err := w.Write(de.Data)
-	if err != nil {
+	if de.open != nil {
+		// Stream data straight from its source
+		if err := de.copyData(w); err != nil {
+			return err
+		}
+	} else if _, err := w.Write(de.Data); err != nil {
 		return err
 	}
 	// Everything must be aligned, so we may skip a few byte when necessary after each resource data
 	b := [dataAlignment]byte{}
-	_, err = w.Write(b[:de.paddedDataSize()-size])
+	_, err := w.Write(b[:de.paddedDataSize()-de.dataSize()])
 	return err
 }
 
@@ -430,7 +502,12 @@ func writeDataEntry(w io.Writer, offset int, dataSize int) error {
 // Reading functions:
 
 func (rs *ResourceSet) read(section []byte, baseAddress uint32, typeID Identifier) error {
-	r := bytes.NewReader(section)
+	return rs.readSection(io.NewSectionReader(bytes.NewReader(section), 0, int64(len(section))), baseAddress, typeID, false)
+}
+
+// readSection reads a resource directory.
+// When lazy is true, data entries keep a reference to the section, instead of a copy of the data.
+func (rs *ResourceSet) readSection(r *io.SectionReader, baseAddress uint32, typeID Identifier, lazy bool) error {
 	return dirEntry{}.walk(r, func(typeEntry dirEntry) error {
 		if typeID != ID(0) &&
 			typeEntry.ident != typeID &&
@@ -441,10 +518,17 @@ func (rs *ResourceSet) read(section []byte, baseAddress uint32, typeID Identifie
 		return typeEntry.walk(r, func(resourceEntry dirEntry) error {
 			resourceEntry.leaf = true
 			return resourceEntry.walk(r, func(langEntry dirEntry) error {
-				data, err := langEntry.readData(r, baseAddress)
+				offset, size, err := langEntry.readData(r, baseAddress)
 				if err != nil {
 					return err
 				}
+				if lazy {
+					return rs.SetReader(typeEntry.ident, resourceEntry.ident, uint16(langEntry.ident.(ID)), size, func() (io.ReadCloser, error) {
+						return io.NopCloser(io.NewSectionReader(r, offset, size)), nil
+					})
+				}
+				data := make([]byte, size)
+				r.ReadAt(data, offset)
 				return rs.Set(typeEntry.ident, resourceEntry.ident, uint16(langEntry.ident.(ID)), data)
 			})
 		})
@@ -458,7 +542,7 @@ type dirEntry struct {
 	leaf   bool
 }
 
-func (de dirEntry) walk(section *bytes.Reader, fn func(dirEntry) error) error {
+func (de dirEntry) walk(section *io.SectionReader, fn func(dirEntry) error) error {
 	entries, err := de.readDirTable(section)
 	if err != nil {
 		return err
@@ -473,28 +557,25 @@ func (de dirEntry) walk(section *bytes.Reader, fn func(dirEntry) error) error {
 	return nil
 }
 
-func (de dirEntry) readData(section *bytes.Reader, baseAddress uint32) ([]byte, error) {
+// readData reads a data entry and returns the offset and the size of the data in the section.
+func (de dirEntry) readData(section *io.SectionReader, baseAddress uint32) (int64, int64, error) {
 	section.Seek(de.offset, io.SeekStart)
 
 	entry := resourceDataEntry{}
 	err := binaryRead(section, &entry)
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
 
 	offset := int64(entry.DataRVA) - int64(baseAddress)
 	if offset < 0 || offset+int64(entry.Size) > section.Size() {
-		return nil, errors.New(errDataEntryOutOfBounds)
+		return 0, 0, errors.New(errDataEntryOutOfBounds)
 	}
 
-	data := make([]byte, entry.Size)
-	section.Seek(offset, io.SeekStart)
-	section.Read(data)
-
-	return data, nil
+	return offset, int64(entry.Size), nil
 }
 
-func (de dirEntry) readDirTable(section *bytes.Reader) ([]dirEntry, error) {
+func (de dirEntry) readDirTable(section *io.SectionReader) ([]dirEntry, error) {
 	section.Seek(de.offset, io.SeekStart)
 
 	table := resourceDirectoryTable{}
@@ -531,7 +612,7 @@ func (de dirEntry) readDirTable(section *bytes.Reader) ([]dirEntry, error) {
 	return entries, nil
 }
 
-func readName(section *bytes.Reader, offset uint32) (Name, error) {
+func readName(section *io.SectionReader, offset uint32) (Name, error) {
 	section.Seek(int64(offset), io.SeekStart)
 
 	var length uint16
@@ -302,3 +302,41 @@ func TestResourceSet_read_DataOutOfBounds2(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTypeEntry_Order_NewLang(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.Set(RT_RCDATA, ID(1), 0x409, []byte{1})
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool { return true })
+
+	// This only adds a language to an existing resource
+	rs.Set(RT_RCDATA, ID(1), 0x40C, []byte{2})
+
+	var langs []uint16
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		langs = append(langs, langID)
+		return true
+	})
+	if len(langs) != 2 || langs[0] != 0x409 || langs[1] != 0x40C {
+		t.Error(langs)
+	}
+
+	// This only removes a language from an existing resource
+	rs.Set(RT_RCDATA, ID(1), 0x409, nil)
+
+	langs = nil
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		langs = append(langs, langID)
+		return true
+	})
+	if len(langs) != 1 || langs[0] != 0x40C {
+		t.Error(langs)
+	}
+	buf := &bytes.Buffer{}
+	if err := rs.WriteDLL(buf, ArchAMD64, DLLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFromEXE(bytes.NewReader(buf.Bytes()))
+	if err != nil || loaded.Get(RT_RCDATA, ID(1), 0x40C) == nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,173 @@
+package winres
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/tc-hib/winres/version"
+)
+
+// Finding describes a problem found by Validate in a resource.
+type Finding struct {
+	TypeID  Identifier
+	ResID   Identifier
+	LangID  uint16
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s/%s/%04X: %s", identString(f.TypeID), identString(f.ResID), f.LangID, f.Message)
+}
+
+// Validate checks the resource set for common mistakes that Windows would silently ignore,
+// such as an icon that would not show in Explorer.
+//
+// It returns a list of findings, in the same order as the resources would be written.
+// An empty list means no problem was found.
+//
+// These are the checks:
+//   - icon and cursor groups must be valid and must only reference existing images
+//   - image sizes declared in groups must match actual image sizes
+//   - the main icon should have a neutral or en-US translation
+//   - the fixed file version of a VERSIONINFO must match its FileVersion strings
+//   - manifests must be valid xml
+//   - string tables must contain exactly 16 strings
+//   - names should be upper case, because FindResource converts names to upper case
+func (rs *ResourceSet) Validate() []Finding {
+	var findings []Finding
+
+	add := func(typeID, resID Identifier, langID uint16, format string, a ...interface{}) {
+		findings = append(findings, Finding{
+			TypeID:  typeID,
+			ResID:   resID,
+			LangID:  langID,
+			Message: fmt.Sprintf(format, a...),
+		})
+	}
+
+	var mainIcon Identifier
+	rs.WalkType(RT_GROUP_ICON, func(resID Identifier, _ uint16, _ []byte) bool {
+		mainIcon = resID
+		return false
+	})
+	if mainIcon != nil && rs.Get(RT_GROUP_ICON, mainIcon, LCIDNeutral) == nil && rs.Get(RT_GROUP_ICON, mainIcon, LCIDDefault) == nil {
+		add(RT_GROUP_ICON, mainIcon, rs.firstLang(RT_GROUP_ICON, mainIcon), "main icon has no neutral or en-US translation")
+	}
+
+	checkedTypes := make(map[Identifier]bool)
+	rs.Walk(func(typeID, resID Identifier, langID uint16, data []byte) bool {
+		if name, ok := typeID.(Name); ok && !checkedTypes[typeID] && !isUpperName(name) {
+			add(typeID, resID, langID, "type name %q contains lower case letters, FindResource would convert it to %q", string(name), strings.ToUpper(string(name)))
+		}
+		checkedTypes[typeID] = true
+		if name, ok := resID.(Name); ok && !isUpperName(name) {
+			add(typeID, resID, langID, "resource name %q contains lower case letters, FindResource would convert it to %q", string(name), strings.ToUpper(string(name)))
+		}
+
+		var messages []string
+		switch typeID {
+		case RT_GROUP_ICON:
+			messages = rs.validateGroup(RT_ICON, data, 0)
+		case RT_GROUP_CURSOR:
+			// In a resource, a cursor image starts with its hot spot
+			messages = rs.validateGroup(RT_CURSOR, data, 4)
+		case RT_VERSION:
+			messages = validateVersionInfo(data)
+		case RT_MANIFEST:
+			if _, err := AppManifestFromXML(data); err != nil {
+				messages = []string{"invalid manifest: " + err.Error()}
+			}
+		case RT_STRING:
+			messages = validateStringBundle(data)
+		}
+		for _, m := range messages {
+			add(typeID, resID, langID, "%s", m)
+		}
+
+		return true
+	})
+
+	return findings
+}
+
+func isUpperName(name Name) bool {
+	return strings.ToUpper(string(name)) == string(name)
+}
+
+// validateGroup checks an RT_GROUP_ICON or an RT_GROUP_CURSOR resource.
+//
+// hdrSize is the size of data preceding the actual image in the resource, but not counted in the group entry.
+func (rs *ResourceSet) validateGroup(imageTypeID ID, data []byte, hdrSize int) []string {
+	groupType := byte(1)
+	if imageTypeID == RT_CURSOR {
+		groupType = 2
+	}
+	ids, err := groupImageIDs(data)
+	if err != nil || data[0] != 0 || data[1] != 0 || data[2] != groupType || data[3] != 0 {
+		return []string{errInvalidGroup}
+	}
+
+	var messages []string
+	for i, id := range ids {
+		img := rs.Get(imageTypeID, ID(id), rs.firstLang(imageTypeID, ID(id)))
+		if img == nil {
+			messages = append(messages, fmt.Sprintf("image #%d (ID %d) is missing", i, id))
+			continue
+		}
+		p := sizeOfGroupHeader + i*sizeOfGroupResEntry + 8
+		bytesInRes := int(data[p+3])<<24 | int(data[p+2])<<16 | int(data[p+1])<<8 | int(data[p])
+		if bytesInRes != len(img) && bytesInRes != len(img)-hdrSize {
+			messages = append(messages, fmt.Sprintf("image #%d (ID %d) is %d bytes long, but the group says %d", i, id, len(img), bytesInRes))
+		}
+	}
+
+	return messages
+}
+
+func validateVersionInfo(data []byte) []string {
+	vi, err := version.FromBytes(data)
+	if err != nil {
+		return []string{"invalid VERSIONINFO: " + err.Error()}
+	}
+
+	var messages []string
+	for _, langID := range vi.LangIDs() {
+		s := vi.Get(langID, version.FileVersion)
+		if s == "" {
+			continue
+		}
+		// Parse the string the same way version.Info does
+		parsed := version.Info{}
+		parsed.SetFileVersion(s)
+		if parsed.FileVersion != vi.FileVersion {
+			v := vi.FileVersion
+			messages = append(messages, fmt.Sprintf("FileVersion string %q in translation %04X does not match fixed file version %d.%d.%d.%d", s, langID, v[0], v[1], v[2], v[3]))
+		}
+	}
+
+	return messages
+}
+
+// validateStringBundle checks an RT_STRING resource, which must be made of 16 strings prefixed by their length.
+func validateStringBundle(data []byte) []string {
+	const stringsPerBundle = 16
+
+	pos := 0
+	for i := 0; i < stringsPerBundle; i++ {
+		if pos+2 > len(data) {
+			return []string{fmt.Sprintf("string bundle is truncated, it contains %d strings instead of %d", i, stringsPerBundle)}
+		}
+		pos += 2 + (int(data[pos+1])<<8|int(data[pos]))*2
+		if pos > len(data) {
+			return []string{fmt.Sprintf("string #%d is longer than the bundle", i)}
+		}
+	}
+
+	// Some tools pad data with zeroes
+	if len(bytes.Trim(data[pos:], "\x00")) > 0 {
+		return []string{fmt.Sprintf("string bundle has %d extra bytes after 16 strings", len(data)-pos)}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package winres
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/tc-hib/winres/version"
+)
+
+func TestResourceSet_Validate(t *testing.T) {
+	rs := &ResourceSet{}
+	if rs.Validate() != nil {
+		t.Fail()
+	}
+
+	rs.SetIcon(Name("APPICON"), newTestIcon(t, 32, 16))
+	rs.SetCursor(ID(1), newTestCursor(t, 32))
+	rs.SetManifest(AppManifest{})
+	vi := version.Info{FileVersion: [4]uint16{1, 2, 3, 4}}
+	vi.Set(0, version.FileVersion, "v1.2.3.4")
+	rs.SetVersionInfo(vi)
+	rs.Set(RT_STRING, ID(1), 0x409, make([]byte, 32))
+	if f := rs.Validate(); f != nil {
+		t.Fatal(f)
+	}
+
+	cursorLen := len(rs.Get(RT_CURSOR, ID(1), 0))
+	rs.Set(RT_ICON, ID(2), 0, nil)
+	group := append([]byte{}, rs.Get(RT_GROUP_CURSOR, ID(1), 0)...)
+	group[6+8]++
+	rs.Set(RT_GROUP_CURSOR, ID(1), 0, group)
+	rs.Set(RT_GROUP_CURSOR, ID(2), 0, []byte{0, 0, 1, 0, 0, 0})
+	vi.Set(0x40C, version.FileVersion, "1.2.3")
+	rs.SetVersionInfo(vi)
+	rs.Set(RT_MANIFEST, ID(2), 0, []byte("<assembly>"))
+	rs.Set(RT_STRING, ID(2), 0x409, make([]byte, 30))
+	rs.Set(RT_STRING, ID(3), 0x409, []byte{0, 0, 16, 0})
+	rs.Set(RT_STRING, ID(4), 0x409, make([]byte, 34))
+	rs.Set(RT_STRING, ID(5), 0x409, append(make([]byte, 32), 1))
+	rs.Set(Name("Custom"), Name("data"), 0x409, []byte{})
+	rs.Set(Name("Custom"), Name("DATA"), 0x40C, []byte{})
+
+	want := []string{
+		`"Custom"/"DATA"/040C: type name "Custom" contains lower case letters, FindResource would convert it to "CUSTOM"`,
+		`"Custom"/"data"/0409: resource name "data" contains lower case letters, FindResource would convert it to "DATA"`,
+		`6/2/0409: string bundle is truncated, it contains 15 strings instead of 16`,
+		`6/3/0409: string #1 is longer than the bundle`,
+		`6/5/0409: string bundle has 1 extra bytes after 16 strings`,
+		fmt.Sprintf(`12/1/0000: image #0 (ID 1) is %d bytes long, but the group says %d`, cursorLen, cursorLen+1),
+		`12/2/0000: invalid group`,
+		`14/"APPICON"/0000: image #1 (ID 2) is missing`,
+		`16/1/040C: FileVersion string "1.2.3" in translation 040C does not match fixed file version 1.2.3.4`,
+		`24/2/0000: invalid manifest: XML syntax error on line 1: unexpected EOF`,
+	}
+
+	var got []string
+	for _, f := range rs.Validate() {
+		got = append(got, f.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate():\n%#v\nwant:\n%#v", got, want)
+	}
+}
+
+func TestResourceSet_Validate_MainIcon(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIconTranslation(ID(1), 0x40C, newTestIcon(t, 16))
+	rs.SetIconTranslation(ID(2), 0, newTestIcon(t, 16))
+
+	f := rs.Validate()
+	if len(f) != 1 || f[0].String() != `14/1/040C: main icon has no neutral or en-US translation` {
+		t.Error(f)
+	}
+
+	rs.SetIconTranslation(ID(1), 0x409, newTestIcon(t, 16))
+	if f = rs.Validate(); f != nil {
+		t.Error(f)
+	}
+}
+
+func TestResourceSet_Validate_InvalidVersion(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.Set(RT_VERSION, ID(1), 0, []byte{1})
+
+	f := rs.Validate()
+	if len(f) != 1 || f[0].Message != "invalid VERSIONINFO: unexpected EOF" {
+		t.Error(f)
+	}
+}
@@ -55,13 +55,18 @@ const (
 )
 
 const (
+	_VOS_UNKNOWN      = 0
 	_VOS_NT_WINDOWS32 = 0x040004
 )
 
 const (
-	_VFT_UNKNOWN = 0
-	_VFT_APP     = 1
-	_VFT_DLL     = 2
+	_VFT_UNKNOWN    = 0
+	_VFT_APP        = 1
+	_VFT_DLL        = 2
+	_VFT_DRV        = 3
+	_VFT_FONT       = 4
+	_VFT_VXD        = 5
+	_VFT_STATIC_LIB = 7
 )
 
 const (
@@ -74,9 +79,9 @@ const (
 func (vi *Info) bytes() []byte {
 	buf := &bytes.Buffer{}
 	writeStructAligned(buf, vi.fixedFileInfo())
-	sfi := stringFileInfoBytes(&vi.lt)
+	sfi := vi.stringFileInfoBytes()
 	writeAligned(buf, sfi)
-	vfi := varFileInfoBytes(&vi.lt)
+	vfi := varFileInfoBytes(vi.Translations())
 	writeAligned(buf, vfi)
 	return nodeBytes(false, vsVersionInfo, buf.Bytes(), sizeOfFixedFileInfo)
 }
@@ -89,8 +94,21 @@ func (vi *Info) fixedFileInfo() _VS_FIXEDFILEINFO {
 		FileVersionLS:    uint32(vi.FileVersion[2])<<16 | uint32(vi.FileVersion[3]),
 		ProductVersionMS: uint32(vi.ProductVersion[0])<<16 | uint32(vi.ProductVersion[1]),
 		ProductVersionLS: uint32(vi.ProductVersion[2])<<16 | uint32(vi.ProductVersion[3]),
-		FileFlagsMask:    _VS_FF_MASK,
-		FileOS:           _VOS_NT_WINDOWS32,
+		FileFlagsMask:    uint32(vi.FlagsMask),
+		FileOS:           uint32(vi.OS),
+		FileSubtype:      uint32(vi.Subtype),
+	}
+	switch vi.FlagsMask {
+	case 0:
+		ffi.FileFlagsMask = _VS_FF_MASK
+	case FlagsMaskNone:
+		ffi.FileFlagsMask = 0
+	}
+	switch vi.OS {
+	case 0:
+		ffi.FileOS = _VOS_NT_WINDOWS32
+	case OSUnknown:
+		ffi.FileOS = _VOS_UNKNOWN
 	}
 	if vi.Flags.Debug {
 		ffi.FileFlags |= _VS_FF_DEBUG
@@ -112,6 +130,14 @@ func (vi *Info) fixedFileInfo() _VS_FIXEDFILEINFO {
 		ffi.FileType = _VFT_APP
 	case DLL:
 		ffi.FileType = _VFT_DLL
+	case Driver:
+		ffi.FileType = _VFT_DRV
+	case Font:
+		ffi.FileType = _VFT_FONT
+	case VXD:
+		ffi.FileType = _VFT_VXD
+	case StaticLib:
+		ffi.FileType = _VFT_STATIC_LIB
 	default:
 		ffi.FileType = _VFT_UNKNOWN
 	}
@@ -119,22 +145,22 @@ func (vi *Info) fixedFileInfo() _VS_FIXEDFILEINFO {
 	return ffi
 }
 
-func stringFileInfoBytes(lt *langTable) []byte {
+func (vi *Info) stringFileInfoBytes() []byte {
 	buf := &bytes.Buffer{}
-	for _, langID := range lt.sortedKeys() {
-		b := stringTableBytes(langID, (*lt)[langID])
+	for _, langID := range vi.lt.sortedKeys() {
+		b := stringTableBytes(langID, vi.CodePage(langID), vi.lt[langID])
 		writeAligned(buf, b)
 	}
 	return nodeBytes(true, stringFileInfo, buf.Bytes(), 0)
 }
 
-func stringTableBytes(langID uint16, strings *stringTable) []byte {
+func stringTableBytes(langID uint16, codePage uint16, strings *stringTable) []byte {
 	buf := &bytes.Buffer{}
 	for _, k := range strings.sortedKeys() {
 		b := stringBytes(k, (*strings)[k])
 		writeAligned(buf, b)
 	}
-	return nodeBytes(true, fmt.Sprintf("%08x", uint32(langID)<<16|codePageUTF16LE), buf.Bytes(), 0)
+	return nodeBytes(true, fmt.Sprintf("%04x%04x", langID, codePage), buf.Bytes(), 0)
 }
 
 func stringBytes(key string, value string) []byte {
@@ -144,11 +170,11 @@ func stringBytes(key string, value string) []byte {
 	return nodeBytes(true, key, buf.Bytes(), len(wValue))
 }
 
-func varFileInfoBytes(lt *langTable) []byte {
+func varFileInfoBytes(translations []Translation) []byte {
 	buf := &bytes.Buffer{}
-	var langs []uint32
-	for _, langID := range lt.sortedKeys() {
-		langs = append(langs, codePageUTF16LE<<16|uint32(langID))
+	langs := make([]uint32, 0, len(translations))
+	for _, t := range translations {
+		langs = append(langs, uint32(t.CodePage)<<16|uint32(t.LangID))
 	}
 	b := varBytes(langs)
 	writeAligned(buf, b)
@@ -224,13 +250,40 @@ func (lt *langTable) sortedKeys() []uint16 {
 
 func fromBytes(data []byte) (*Info, error) {
 	vi := &Info{}
-	_, err := vi.readNode(nil, data)
+	var err error
+	if isLegacyLayout(data) {
+		_, err = vi.readNode16(nil, data)
+	} else {
+		_, err = vi.readNode(nil, data)
+	}
 	if err != nil {
 		return nil, err
 	}
+
+	// Only keep the Translation array if it is not the one Bytes would generate
+	if vi.translations != nil {
+		translations := vi.translations
+		vi.translations = nil
+		if !equalTranslations(translations, vi.Translations()) {
+			vi.translations = translations
+		}
+	}
+
 	return vi, nil
 }
 
+func equalTranslations(a, b []Translation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Returns the number of bytes read
 func (vi *Info) readNode(parent interface{}, data []byte) (int, error) {
 	n, pos, err := readNodeHeader(data)
@@ -266,8 +319,14 @@ func (vi *Info) readNode(parent interface{}, data []byte) (int, error) {
 		}
 
 	case parent == vsVersionInfo && key == varFileInfo:
-		// skip these nodes
-		break
+		err := vi.readChildren(key, data, &pos)
+		if err != nil {
+			return 0, err
+		}
+
+	case parent == varFileInfo && key == translation:
+		pos = align(pos)
+		vi.translations = readTranslations(data[pos : pos+int(n.ValueLength)])
 
 	case parent == vsVersionInfo && key == stringFileInfo:
 		err := vi.readChildren(key, data, &pos)
@@ -281,9 +340,8 @@ func (vi *Info) readNode(parent interface{}, data []byte) (int, error) {
 		if err != nil {
 			return 0, errors.New(errInvalidLangID)
 		}
-		if codePage != codePageUTF16LE {
-			return 0, errors.New(errUnhandledCodePage)
-		}
+		// In a 32-bit VERSIONINFO, strings are always UTF-16, whatever the code page says
+		vi.SetCodePage(langID, codePage)
 		err = vi.readChildren(langID, data, &pos)
 		if err != nil {
 			return 0, err
@@ -304,6 +362,116 @@ func (vi *Info) readNode(parent interface{}, data []byte) (int, error) {
 	return len(data), nil
 }
 
+// isLegacyLayout tells if data is a 16-bit VS_VERSIONINFO, whose header has no wType member,
+// and whose strings are ANSI.
+func isLegacyLayout(data []byte) bool {
+	return len(data) > 4+len(vsVersionInfo) && string(data[4:5+len(vsVersionInfo)]) == vsVersionInfo+"\x00"
+}
+
+// readNode16 is readNode for the 16-bit layout.
+// Returns the number of bytes read
+func (vi *Info) readNode16(parent interface{}, data []byte) (int, error) {
+	if len(data) < 4 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	length := int(data[1])<<8 | int(data[0])
+	valueLength := int(data[3])<<8 | int(data[2])
+	if length < 4 || length > len(data) {
+		return 0, errors.New(errInvalidLength)
+	}
+	data = data[:length]
+
+	pos := 4
+	key, err := readANSIString(data, &pos)
+	if err != nil {
+		return 0, err
+	}
+
+	if valueLength > 0 && align(pos)+valueLength > len(data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	switch true {
+	case parent == nil && key == vsVersionInfo:
+		if valueLength >= sizeOfFixedFileInfo {
+			err := vi.readFixedStruct(data[align(pos):])
+			if err != nil {
+				return 0, err
+			}
+		}
+		pos = align(pos) + valueLength
+		err := vi.readChildren16(key, data, &pos)
+		if err != nil {
+			return 0, err
+		}
+
+	case parent == vsVersionInfo && key == varFileInfo:
+		err := vi.readChildren16(key, data, &pos)
+		if err != nil {
+			return 0, err
+		}
+
+	case parent == varFileInfo && key == translation:
+		pos = align(pos)
+		vi.translations = readTranslations(data[pos : pos+valueLength])
+
+	case parent == vsVersionInfo && key == stringFileInfo:
+		err := vi.readChildren16(key, data, &pos)
+		if err != nil {
+			return 0, err
+		}
+
+	case parent == stringFileInfo:
+		var langID, codePage uint16
+		_, err := fmt.Sscanf(key, "%04x%04x", &langID, &codePage)
+		if err != nil {
+			return 0, errors.New(errInvalidLangID)
+		}
+		vi.SetCodePage(langID, codePage)
+		err = vi.readChildren16(langID, data, &pos)
+		if err != nil {
+			return 0, err
+		}
+
+	default:
+		// Under a language ID, this is a key/value pair
+		if id, ok := parent.(uint16); ok {
+			pos = align(pos)
+			value := bytes.TrimRight(data[pos:pos+valueLength], "\x00")
+			s, err := decodeANSI(vi.CodePage(id), value)
+			if err != nil {
+				return 0, err
+			}
+			vi.Set(id, key, s)
+		}
+	}
+
+	return len(data), nil
+}
+
+func (vi *Info) readChildren16(parent interface{}, data []byte, pos *int) error {
+	for align(*pos) < len(data) {
+		*pos = align(*pos)
+		offset, err := vi.readNode16(parent, data[*pos:])
+		if err != nil {
+			return err
+		}
+		*pos += offset
+	}
+	return nil
+}
+
+func readTranslations(data []byte) []Translation {
+	translations := []Translation{}
+	for i := 0; i+4 <= len(data); i += 4 {
+		translations = append(translations, Translation{
+			LangID:   uint16(data[i+1])<<8 | uint16(data[i]),
+			CodePage: uint16(data[i+3])<<8 | uint16(data[i+2]),
+		})
+	}
+	return translations
+}
+
 func (vi *Info) readChildren(parent interface{}, data []byte, pos *int) error {
 	for align(*pos) < len(data) {
 		*pos = align(*pos)
@@ -328,9 +496,34 @@ func (vi *Info) readFixedStruct(data []byte) error {
 		vi.Type = App
 	case _VFT_DLL:
 		vi.Type = DLL
+	case _VFT_DRV:
+		vi.Type = Driver
+	case _VFT_FONT:
+		vi.Type = Font
+	case _VFT_VXD:
+		vi.Type = VXD
+	case _VFT_STATIC_LIB:
+		vi.Type = StaticLib
 	default:
 		vi.Type = Unknown
 	}
+	vi.Subtype = fileSubtype(fixed.FileSubtype)
+	switch fixed.FileFlagsMask {
+	case _VS_FF_MASK:
+		vi.FlagsMask = 0
+	case 0:
+		vi.FlagsMask = FlagsMaskNone
+	default:
+		vi.FlagsMask = fileFlagsMask(fixed.FileFlagsMask)
+	}
+	switch fixed.FileOS {
+	case _VOS_NT_WINDOWS32:
+		vi.OS = 0
+	case _VOS_UNKNOWN:
+		vi.OS = OSUnknown
+	default:
+		vi.OS = fileOS(fixed.FileOS)
+	}
 	flags := fixed.FileFlags & _VS_FF_MASK
 	vi.Flags.Debug = flags&_VS_FF_DEBUG != 0
 	vi.Flags.Prerelease = flags&_VS_FF_PRERELEASE != 0
@@ -373,6 +566,18 @@ func readString(data []byte, pos *int) (string, error) {
 	return string(utf16.Decode(wKey)), nil
 }
 
+// readANSIString reads a NUL terminated key in the 16-bit layout.
+func readANSIString(data []byte, pos *int) (string, error) {
+	end := bytes.IndexByte(data[*pos:], 0)
+	if end <= 0 {
+		*pos = len(data)
+		return "", io.ErrUnexpectedEOF
+	}
+	key, err := decodeANSI(codePageLatin1, data[*pos:*pos+end])
+	*pos += end + 1
+	return key, err
+}
+
 func readStringWithLength(data []byte, pos *int, length int) (string, error) {
 	data = data[*pos:]
 
@@ -0,0 +1,104 @@
+package version
+
+// In this file are functions to fill an Info structure from the build information embedded by the Go toolchain.
+// This is what `go version -m` displays.
+
+import (
+	"debug/buildinfo"
+	"io"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// FromBuildInfo makes an Info from the build information of a Go program,
+// such as returned by debug.ReadBuildInfo.
+//
+//   - ProductName is the last element of the main module path, without its major version suffix
+//   - ProductVersion is the main module version, unless it is "(devel)"
+//   - Comments tell the VCS revision and time, and whether the working tree was modified
+//   - Flags.Patched is set when the working tree was modified
+//   - Timestamp is the VCS time
+//
+// The strings are set in the neutral language.
+func FromBuildInfo(bi *debug.BuildInfo) *Info {
+	vi := &Info{}
+	if bi == nil {
+		return vi
+	}
+
+	modPath := bi.Main.Path
+	if modPath == "" {
+		modPath = bi.Path
+	}
+	if name := productNameFromPath(modPath); name != "" {
+		vi.Set(LangNeutral, ProductName, name)
+	}
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		vi.SetProductVersion(strings.TrimPrefix(v, "v"))
+	}
+
+	var vcs, revision, vcsTime string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs":
+			vcs = s.Value
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.time":
+			vcsTime = s.Value
+		case "vcs.modified":
+			vi.Flags.Patched = s.Value == "true"
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, vcsTime); err == nil {
+		vi.Timestamp = t
+	}
+
+	var comments []string
+	if revision != "" {
+		if vcs == "" {
+			vcs = "vcs"
+		}
+		comments = append(comments, vcs+" revision "+revision)
+	}
+	if vcsTime != "" {
+		comments = append(comments, vcsTime)
+	}
+	if vi.Flags.Patched {
+		comments = append(comments, "modified")
+	}
+	if len(comments) > 0 {
+		vi.Set(LangNeutral, Comments, strings.Join(comments, ", "))
+	}
+
+	return vi
+}
+
+// FromGoBinary makes an Info from the build information of a compiled Go program.
+//
+// See FromBuildInfo.
+func FromGoBinary(r io.ReaderAt) (*Info, error) {
+	bi, err := buildinfo.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromBuildInfo(bi), nil
+}
+
+// productNameFromPath returns "tool" for "example.com/tool/v2".
+func productNameFromPath(p string) string {
+	name := path.Base(p)
+	if isMajorVersionSuffix(name) && path.Dir(p) != "." {
+		name = path.Base(path.Dir(p))
+	}
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+func isMajorVersionSuffix(s string) bool {
+	return len(s) > 1 && s[0] == 'v' && isSemverNumber(s[1:])
+}
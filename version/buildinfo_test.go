@@ -0,0 +1,90 @@
+package version
+
+import (
+	"bytes"
+	"os"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Path: "example.com/tool/v2/cmd/tool",
+		Main: debug.Module{Path: "example.com/tool/v2", Version: "v2.1.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "-trimpath", Value: "true"},
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "3f2a1b0c4d5e6f708192a3b4c5d6e7f809102030"},
+			{Key: "vcs.time", Value: "2024-03-01T10:20:30Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	vi := FromBuildInfo(bi)
+	if vi.Get(LangNeutral, ProductName) != "tool" ||
+		vi.Get(LangNeutral, ProductVersion) != "2.1.3" ||
+		vi.ProductVersion != [4]uint16{2, 1, 3, 0} ||
+		vi.Get(LangNeutral, Comments) != "git revision 3f2a1b0c4d5e6f708192a3b4c5d6e7f809102030, 2024-03-01T10:20:30Z, modified" ||
+		!vi.Flags.Patched ||
+		!vi.Timestamp.Equal(time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)) {
+		t.Errorf("%+v", vi)
+	}
+
+	bi = &debug.BuildInfo{
+		Path: "command-line-arguments",
+		Main: debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.modified", Value: "false"},
+		},
+	}
+	vi = FromBuildInfo(bi)
+	if len(vi.LangIDs()) != 1 || len(*vi.lt[LangNeutral]) != 1 ||
+		vi.Get(LangNeutral, ProductName) != "command-line-arguments" ||
+		vi.Flags.Patched || !vi.Timestamp.IsZero() {
+		t.Errorf("%+v", vi)
+	}
+
+	if vi = FromBuildInfo(nil); vi == nil || len(vi.LangIDs()) != 0 {
+		t.Fail()
+	}
+}
+
+func Test_productNameFromPath(t *testing.T) {
+	for p, name := range map[string]string{
+		"github.com/tc-hib/winres":     "winres",
+		"example.com/tool/v2":          "tool",
+		"example.com/tool/v0x":         "v0x",
+		"example.com/tool/cmd/v10tool": "v10tool",
+		"v2":                           "v2",
+		"":                             "",
+	} {
+		if got := productNameFromPath(p); got != name {
+			t.Errorf("%q: expected %q, got %q", p, name, got)
+		}
+	}
+}
+
+func TestFromGoBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+
+	vi, err := FromGoBinary(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.Get(LangNeutral, ProductName) != "winres" {
+		t.Error(vi.Get(LangNeutral, ProductName))
+	}
+
+	_, err = FromGoBinary(bytes.NewReader([]byte("not a Go binary")))
+	if err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,35 @@
+package version
+
+import "testing"
+
+func Test_decodeANSI(t *testing.T) {
+	tests := []struct {
+		codePage uint16
+		s        string
+		want     string
+		err      string
+	}{
+		{1252, "Caf\xe9 \x80 \x93ok\x94 \x81", "Café € “ok” \u0081", ""},
+		{1250, "\x8a\xe8\xf8", "Ščř", ""},
+		{1251, "\xcf\xf0\xe8\xe2\xe5\xf2", "Привет", ""},
+		{1253, "\xc1\xe8\xde\xed\xe1", "Αθήνα", ""},
+		{874, "\xa1\xd2", "กา", ""},
+		{28591, "\x80\xff", "\u0080ÿ", ""},
+		{65001, "\xe2\x82\xac", "€", ""},
+		{65001, "\xe2\x82", "", errInvalidString},
+		{932, "\x82\xa0", "", errUnhandledCodePage},
+		{1200, "a", "", errUnhandledCodePage},
+	}
+	for _, tt := range tests {
+		got, err := decodeANSI(tt.codePage, []byte(tt.s))
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("decodeANSI(%d, %q) error = %v, want %q", tt.codePage, tt.s, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("decodeANSI(%d, %q) = %q, %v, want %q", tt.codePage, tt.s, got, err, tt.want)
+		}
+	}
+}
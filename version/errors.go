@@ -4,8 +4,13 @@ const (
 	errInvalidSignature    = "invalid fixed file info signature"
 	errInvalidLength       = "invalid length"
 	errInvalidLangID       = "invalid language id"
-	errUnhandledCodePage   = "unhandled code page"
 	errInvalidStringLength = "invalid string length"
+	errInvalidString       = "invalid string"
+	errUnhandledCodePage   = "unhandled code page"
+
+	errInvalidSemver     = "invalid semantic version"
+	errVersionTooBig     = "version number above 65535"
+	errInvalidFixedValue = "invalid value in fixed file info"
 
 	errEmptyKey         = "empty key"
 	errKeyContainsNUL   = "invalid key contains NUL character"
@@ -2,16 +2,23 @@ package version
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tc-hib/winres/lcid"
 )
 
 type jsonFixed struct {
 	FileVersion    string     `json:"file_version,omitempty"`
 	ProductVersion string     `json:"product_version,omitempty"`
 	Flags          string     `json:"flags,omitempty"`
+	FlagsMask      string     `json:"flags_mask,omitempty"`
+	OS             string     `json:"os,omitempty"`
 	Type           string     `json:"type,omitempty"`
+	Subtype        string     `json:"subtype,omitempty"`
 	Timestamp      *time.Time `json:"timestamp,omitempty"`
 }
 
@@ -50,19 +57,54 @@ func (vi *Info) MarshalJSON() ([]byte, error) {
 		// This is the default, omit it
 	case DLL:
 		jf.Type = "DLL"
+	case Driver:
+		jf.Type = "Driver"
+	case Font:
+		jf.Type = "Font"
+	case VXD:
+		jf.Type = "VxD"
+	case StaticLib:
+		jf.Type = "StaticLib"
 	default:
 		jf.Type = "Unknown"
 	}
+	if vi.Subtype != 0 {
+		jf.Subtype = subtypeNames(vi.Type)[vi.Subtype]
+		if jf.Subtype == "" {
+			jf.Subtype = fmt.Sprintf("0x%X", uint32(vi.Subtype))
+		}
+	}
+	switch vi.FlagsMask {
+	case 0:
+		// This is the default, omit it
+	case FlagsMaskNone:
+		jf.FlagsMask = "0x0"
+	default:
+		jf.FlagsMask = fmt.Sprintf("0x%X", uint32(vi.FlagsMask))
+	}
+	if vi.OS != 0 {
+		jf.OS = osNames[vi.OS]
+		if jf.OS == "" {
+			jf.OS = fmt.Sprintf("0x%X", uint32(vi.OS))
+		}
+	}
 	if !vi.Timestamp.IsZero() {
 		jf.Timestamp = &vi.Timestamp
 	}
-	if jf.FileVersion != "" || jf.ProductVersion != "" || jf.Flags != "" || jf.Type != "" || jf.Timestamp != nil {
+	if jf.FileVersion != "" || jf.ProductVersion != "" || jf.Flags != "" || jf.FlagsMask != "" || jf.OS != "" ||
+		jf.Type != "" || jf.Subtype != "" || jf.Timestamp != nil {
 		jvi.Fixed = &jf
 	}
 
 	jvi.Info = make(map[string]*stringTable)
 	for k, v := range vi.lt {
-		if v != nil {
+		if v == nil {
+			continue
+		}
+		if cp := vi.CodePage(k); cp != codePageUTF16LE {
+			// Same as the string table key, e.g. "040904E4"
+			jvi.Info[fmt.Sprintf("%04X%04X", k, cp)] = v
+		} else {
 			jvi.Info[fmt.Sprintf("%04X", k)] = v
 		}
 	}
@@ -70,6 +112,9 @@ func (vi *Info) MarshalJSON() ([]byte, error) {
 	return json.Marshal(jvi)
 }
 
+// UnmarshalJSON reads the JSON representation of an Info structure.
+//
+// Languages are LCIDs in hexadecimal, such as "040C", or BCP 47 tags, such as "fr-FR".
 func (vi *Info) UnmarshalJSON(b []byte) error {
 	jvi := &jsonVersionInfo{}
 	if err := json.Unmarshal(b, jvi); err != nil {
@@ -91,9 +136,51 @@ func (vi *Info) UnmarshalJSON(b []byte) error {
 			vi.Type = App
 		case "dll":
 			vi.Type = DLL
+		case "driver":
+			vi.Type = Driver
+		case "font":
+			vi.Type = Font
+		case "vxd":
+			vi.Type = VXD
+		case "staticlib":
+			vi.Type = StaticLib
 		default:
 			vi.Type = Unknown
 		}
+		if jf.Subtype != "" {
+			v, err := parseFixedValue(jf.Subtype, subtypeLookup(vi.Type))
+			if err != nil {
+				return err
+			}
+			vi.Subtype = fileSubtype(v)
+		}
+		if jf.FlagsMask != "" {
+			v, err := parseFixedValue(jf.FlagsMask, nil)
+			if err != nil {
+				return err
+			}
+			switch v {
+			case 0:
+				vi.FlagsMask = FlagsMaskNone
+			case uint32(FlagsMaskDefault):
+				vi.FlagsMask = 0
+			default:
+				vi.FlagsMask = fileFlagsMask(v)
+			}
+		}
+		if jf.OS != "" {
+			v, err := parseFixedValue(jf.OS, lookupOS)
+			if err != nil {
+				return err
+			}
+			vi.OS = fileOS(v)
+			switch vi.OS {
+			case OSNTWindows32:
+				vi.OS = 0
+			case 0:
+				vi.OS = OSUnknown
+			}
+		}
 		if jf.Timestamp != nil {
 			vi.Timestamp = *jf.Timestamp
 		}
@@ -101,7 +188,18 @@ func (vi *Info) UnmarshalJSON(b []byte) error {
 
 	vi.lt = make(langTable)
 	for h, v := range jvi.Info {
-		var k uint16
+		var k, cp uint16
+		if len(h) == 8 {
+			if _, err := fmt.Sscanf(h, "%04X%04X", &k, &cp); err == nil {
+				vi.lt[k] = v
+				vi.SetCodePage(k, cp)
+			}
+			continue
+		}
+		if id, err := lcid.Parse(h); err == nil {
+			vi.lt[id] = v
+			continue
+		}
 		_, err := fmt.Sscanf(h, "%X", &k)
 		if err == nil {
 			vi.lt[k] = v
@@ -110,3 +208,79 @@ func (vi *Info) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+var osNames = map[fileOS]string{
+	OSUnknown:      "Unknown",
+	OSDOS:          "DOS",
+	OSNT:           "NT",
+	OSWindows16:    "Windows16",
+	OSWindows32:    "Windows32",
+	OSDOSWindows16: "DOS_Windows16",
+	OSDOSWindows32: "DOS_Windows32",
+	OSNTWindows32:  "NT_Windows32",
+}
+
+var driverSubtypeNames = map[fileSubtype]string{
+	DriverPrinter:          "Printer",
+	DriverKeyboard:         "Keyboard",
+	DriverLanguage:         "Language",
+	DriverDisplay:          "Display",
+	DriverMouse:            "Mouse",
+	DriverNetwork:          "Network",
+	DriverSystem:           "System",
+	DriverInstallable:      "Installable",
+	DriverSound:            "Sound",
+	DriverComm:             "Comm",
+	DriverVersionedPrinter: "VersionedPrinter",
+}
+
+var fontSubtypeNames = map[fileSubtype]string{
+	FontRaster:   "Raster",
+	FontVector:   "Vector",
+	FontTrueType: "TrueType",
+}
+
+func lookupOS(name string) (uint32, bool) {
+	for v, n := range osNames {
+		if strings.EqualFold(name, n) {
+			return uint32(v), true
+		}
+	}
+	return 0, false
+}
+
+func subtypeLookup(t fileType) func(name string) (uint32, bool) {
+	names := subtypeNames(t)
+	return func(name string) (uint32, bool) {
+		for v, n := range names {
+			if strings.EqualFold(name, n) {
+				return uint32(v), true
+			}
+		}
+		return 0, false
+	}
+}
+
+func subtypeNames(t fileType) map[fileSubtype]string {
+	switch t {
+	case Driver:
+		return driverSubtypeNames
+	case Font:
+		return fontSubtypeNames
+	}
+	return nil
+}
+
+// parseFixedValue parses a name that lookup knows, or a number such as "0x17".
+func parseFixedValue(s string, lookup func(name string) (uint32, bool)) (uint32, error) {
+	if lookup != nil {
+		if v, ok := lookup(s); ok {
+			return v, nil
+		}
+	}
+	v, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, errors.New(errInvalidFixedValue)
+	}
+	return uint32(v), nil
+}
@@ -2,6 +2,7 @@ package version
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -62,6 +63,55 @@ func TestInfo_MarshalJSON(t *testing.T) {
 	checkMarshal(t, vi, `{}`)
 }
 
+func TestInfo_MarshalJSON_CodePage(t *testing.T) {
+	vi := &Info{}
+	vi.Set(0x409, ProductName, "Product")
+	vi.Set(0x40C, ProductName, "Produit")
+	vi.SetCodePage(0x409, 1252)
+	checkMarshal(t, vi, `{"info":{"040904E4":{"ProductName":"Product"},"040C":{"ProductName":"Produit"}}}`)
+
+	vi = unmarshal(t, `{"info":{"041104e4":{"ProductName":"x"},"0411FFFF1":{"ProductName":"y"}}}`)
+	if vi.CodePage(0x411) != 1252 || vi.Get(0x411, ProductName) != "x" || len(vi.LangIDs()) != 1 {
+		t.Fail()
+	}
+}
+
+func TestInfo_MarshalJSON_Fixed(t *testing.T) {
+	vi := &Info{Type: Driver, Subtype: DriverKeyboard, OS: OSNT, FlagsMask: 0x17}
+	checkMarshal(t, vi, `{"fixed":{"flags_mask":"0x17","os":"NT","type":"Driver","subtype":"Keyboard"}}`)
+
+	vi = &Info{Type: Font, Subtype: FontTrueType, OS: OSUnknown, FlagsMask: FlagsMaskNone}
+	checkMarshal(t, vi, `{"fixed":{"flags_mask":"0x0","os":"Unknown","type":"Font","subtype":"TrueType"}}`)
+
+	vi = &Info{Type: VXD, Subtype: 0x1234, OS: 0x50001}
+	checkMarshal(t, vi, `{"fixed":{"os":"0x50001","type":"VxD","subtype":"0x1234"}}`)
+
+	vi = &Info{Type: StaticLib}
+	checkMarshal(t, vi, `{"fixed":{"type":"StaticLib"}}`)
+
+	vi = unmarshal(t, `{"fixed":{"flags_mask":"0x3f","os":"nt_windows32","type":"driver","subtype":"12"}}`)
+	if vi.FlagsMask != 0 || vi.OS != 0 || vi.Type != Driver || vi.Subtype != DriverVersionedPrinter {
+		t.Fail()
+	}
+
+	for _, s := range []string{
+		`{"fixed":{"os":"Windows95"}}`,
+		`{"fixed":{"flags_mask":"0x100000000"}}`,
+		`{"fixed":{"type":"Font","subtype":"Keyboard"}}`,
+	} {
+		if err := json.Unmarshal([]byte(s), &Info{}); err == nil || err.Error() != errInvalidFixedValue {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+}
+
+func TestInfo_UnmarshalJSON_Tags(t *testing.T) {
+	vi := unmarshal(t, `{"info":{"fr-FR":{"ProductName":"Produit"},"en_us":{"ProductName":"Product"},"0":{"Comments":"x"},"de":{"ProductName":"Produkt"}}}`)
+	if !reflect.DeepEqual(vi.LangIDs(), []uint16{0, 0x7, 0x409, 0x40C}) || vi.Get(0x40C, ProductName) != "Produit" {
+		t.Error(vi.LangIDs())
+	}
+}
+
 func TestInfo_UnmarshalJSON(t *testing.T) {
 	var vi Info
 	if vi.UnmarshalJSON([]byte(` {,}`)) == nil {
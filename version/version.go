@@ -6,7 +6,10 @@ package version
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,6 +35,64 @@ const (
 	App fileType = iota
 	DLL
 	Unknown
+	Driver
+	Font
+	VXD
+	StaticLib
+)
+
+// fileSubtype is the function of a driver or font, or the identifier of a virtual device.
+type fileSubtype uint32
+
+// Driver subtypes
+const (
+	DriverPrinter          fileSubtype = 0x1
+	DriverKeyboard         fileSubtype = 0x2
+	DriverLanguage         fileSubtype = 0x3
+	DriverDisplay          fileSubtype = 0x4
+	DriverMouse            fileSubtype = 0x5
+	DriverNetwork          fileSubtype = 0x6
+	DriverSystem           fileSubtype = 0x7
+	DriverInstallable      fileSubtype = 0x8
+	DriverSound            fileSubtype = 0x9
+	DriverComm             fileSubtype = 0xA
+	DriverVersionedPrinter fileSubtype = 0xC
+)
+
+// Font subtypes
+const (
+	FontRaster   fileSubtype = 0x1
+	FontVector   fileSubtype = 0x2
+	FontTrueType fileSubtype = 0x3
+)
+
+// fileOS is the operating system a file was designed for, as found in dwFileOS.
+//
+// The zero value stands for OSNTWindows32, which is the default.
+type fileOS uint32
+
+const (
+	OSDOS          fileOS = 0x10000
+	OSNT           fileOS = 0x40000
+	OSWindows16    fileOS = 0x1
+	OSWindows32    fileOS = 0x4
+	OSDOSWindows16 fileOS = 0x10001
+	OSDOSWindows32 fileOS = 0x10004
+	OSNTWindows32  fileOS = 0x40004
+	// OSUnknown stands for VOS_UNKNOWN, whose value is zero in the binary format.
+	OSUnknown fileOS = 0xFFFFFFFF
+)
+
+// fileFlagsMask tells which bits of dwFileFlags are valid.
+//
+// The zero value stands for the usual mask, VS_FFI_FILEFLAGSMASK.
+type fileFlagsMask uint32
+
+const (
+	// FlagsMaskDefault is VS_FFI_FILEFLAGSMASK, meaning every flag is valid.
+	FlagsMaskDefault fileFlagsMask = 0x3F
+	// FlagsMaskNone stands for a zero mask in the binary format.
+	FlagsMaskNone fileFlagsMask = 0xFFFFFFFF
 )
 
 const (
@@ -48,9 +109,14 @@ type Info struct {
 	FileVersion    [4]uint16
 	ProductVersion [4]uint16
 	Flags          versionFlags
+	FlagsMask      fileFlagsMask
+	OS             fileOS
 	Type           fileType
+	Subtype        fileSubtype // Only meaningful for Driver, Font and VXD
 	Timestamp      time.Time
 	lt             langTable
+	codePages      map[uint16]uint16 // Code page of string tables, when it is not 1200
+	translations   []Translation     // VarFileInfo\Translation, when it is not made from string tables
 
 	// temporary state
 	pos int
@@ -67,6 +133,14 @@ type versionFlags struct {
 
 type langTable map[uint16]*stringTable
 
+// Translation is a language and code page pair, as found in the Translation array of VarFileInfo.
+//
+// This array tells which languages the file supports.
+type Translation struct {
+	LangID   uint16
+	CodePage uint16
+}
+
 type stringTable map[string]string
 
 // Set sets a key/value pair in the Info structure for a specific locale.
@@ -100,6 +174,77 @@ func (vi *Info) Set(langID uint16, key string, value string) error {
 	return nil
 }
 
+// Get returns the value of a key for a specific locale.
+//
+// It returns an empty string if the key is not set.
+func (vi *Info) Get(langID uint16, key string) string {
+	st := vi.lt[langID]
+	if st == nil {
+		return ""
+	}
+	return (*st)[key]
+}
+
+// LangIDs returns the language IDs of every string table, in ascending order.
+func (vi *Info) LangIDs() []uint16 {
+	return vi.lt.sortedKeys()
+}
+
+// Translations returns the content of the Translation array that Bytes will write in VarFileInfo.
+//
+// Unless it was set by SetTranslations or read by FromBytes, it is made from the string tables,
+// with their language ID and code page.
+func (vi *Info) Translations() []Translation {
+	if vi.translations != nil {
+		return append([]Translation{}, vi.translations...)
+	}
+	translations := []Translation{}
+	for _, langID := range vi.lt.sortedKeys() {
+		translations = append(translations, Translation{LangID: langID, CodePage: vi.CodePage(langID)})
+	}
+	return translations
+}
+
+// SetTranslations sets the content of the Translation array in VarFileInfo.
+//
+// Code pages can be any Windows code page, such as 1252 (Windows Latin 1), or 1200 (Unicode).
+//
+// A nil slice restores the default behavior, which is to list the string tables.
+// An empty slice means an empty array.
+func (vi *Info) SetTranslations(translations []Translation) {
+	if translations == nil {
+		vi.translations = nil
+		return
+	}
+	vi.translations = append([]Translation{}, translations...)
+}
+
+// CodePage returns the code page of a string table, which is part of its key in the StringFileInfo block.
+//
+// It is 1200 (Unicode) unless it was set by SetCodePage or read by FromBytes.
+func (vi *Info) CodePage(langID uint16) uint16 {
+	if cp, ok := vi.codePages[langID]; ok {
+		return cp
+	}
+	return codePageUTF16LE
+}
+
+// SetCodePage sets the code page of a string table, for example 1252 to get the key "040904e4"
+// that many older tools emit for en-US.
+//
+// Strings are always stored as UTF-16 in a 32-bit VERSIONINFO, so this only changes the key,
+// and the default content of the Translation array.
+func (vi *Info) SetCodePage(langID uint16, codePage uint16) {
+	if codePage == codePageUTF16LE {
+		delete(vi.codePages, langID)
+		return
+	}
+	if vi.codePages == nil {
+		vi.codePages = make(map[uint16]uint16)
+	}
+	vi.codePages[langID] = codePage
+}
+
 // Bytes returns the binary representation of the VS_VERSIONINFO struct.
 func (vi *Info) Bytes() []byte {
 	if vi == nil {
@@ -109,6 +254,10 @@ func (vi *Info) Bytes() []byte {
 }
 
 // FromBytes loads an Info from the binary representation of a VS_VERSIONINFO struct.
+//
+// It also reads the legacy 16-bit layout, whose strings are decoded from their code page,
+// as long as it is UTF-8 or one of the single-byte Windows code pages (874, 1250 to 1258).
+// Bytes will then write a 32-bit VS_VERSIONINFO with the same string table keys.
 func FromBytes(data []byte) (*Info, error) {
 	return fromBytes(data)
 }
@@ -130,13 +279,7 @@ func (vi *Info) SplitTranslations() map[uint16]*Info {
 // This should be called after json.Unmarshal to override the version.
 func (vi *Info) SetProductVersion(productVersion string) {
 	vi.ProductVersion = versionStringToArray(productVersion)
-	if len(vi.lt) == 0 {
-		vi.Set(LangNeutral, ProductVersion, productVersion)
-		return
-	}
-	for langID := range vi.lt {
-		vi.Set(langID, ProductVersion, productVersion)
-	}
+	vi.setAll(ProductVersion, productVersion)
 }
 
 // SetFileVersion sets the file version, ensuring this is the only one in the structure.
@@ -144,15 +287,111 @@ func (vi *Info) SetProductVersion(productVersion string) {
 // This should be called after json.Unmarshal to override the version.
 func (vi *Info) SetFileVersion(fileVersion string) {
 	vi.FileVersion = versionStringToArray(fileVersion)
+	vi.setAll(FileVersion, fileVersion)
+}
+
+// SetFromSemver sets the versions from a semantic version string, such as "v1.4.0-rc.2+abc123",
+// and a build number.
+//
+// FileVersion and ProductVersion become major.minor.patch.build, which means each number must not exceed 65535.
+//
+// The ProductVersion string is the semantic version, and the FileVersion string is the four numbers.
+//
+// A pre-release such as "rc.2" sets the Prerelease and SpecialBuild flags, and becomes the SpecialBuild string.
+// Build metadata such as "abc123" sets the PrivateBuild flag, and becomes the PrivateBuild string.
+// Without them, the flags are cleared and the strings are removed.
+//
+// Like SetProductVersion, this should be called after json.Unmarshal, and sets strings in every language.
+func (vi *Info) SetFromSemver(v string, build uint16) error {
+	v = strings.TrimPrefix(v, "v")
+	core, metadata, hasMetadata := strings.Cut(v, "+")
+	core, prerelease, hasPrerelease := strings.Cut(core, "-")
+	if hasMetadata && !isValidSemverIdentifiers(metadata, false) ||
+		hasPrerelease && !isValidSemverIdentifiers(prerelease, true) {
+		return errors.New(errInvalidSemver)
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return errors.New(errInvalidSemver)
+	}
+	var ver [4]uint16
+	for i, p := range parts {
+		if !isSemverNumber(p) {
+			return errors.New(errInvalidSemver)
+		}
+		n, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return errors.New(errVersionTooBig)
+		}
+		ver[i] = uint16(n)
+	}
+	ver[3] = build
+
+	vi.FileVersion = ver
+	vi.ProductVersion = ver
+	vi.setAll(FileVersion, fmt.Sprintf("%d.%d.%d.%d", ver[0], ver[1], ver[2], ver[3]))
+	vi.setAll(ProductVersion, v)
+	vi.Flags.Prerelease = hasPrerelease
+	vi.Flags.SpecialBuild = hasPrerelease
+	if hasPrerelease {
+		vi.setAll(SpecialBuild, prerelease)
+	} else {
+		vi.deleteAll(SpecialBuild)
+	}
+	vi.Flags.PrivateBuild = hasMetadata
+	if hasMetadata {
+		vi.setAll(PrivateBuild, metadata)
+	} else {
+		vi.deleteAll(PrivateBuild)
+	}
+	return nil
+}
+
+// setAll sets a key/value pair in every language, or in the neutral language when there is none.
+func (vi *Info) setAll(key string, value string) {
 	if len(vi.lt) == 0 {
-		vi.Set(LangNeutral, FileVersion, fileVersion)
+		vi.Set(LangNeutral, key, value)
 		return
 	}
 	for langID := range vi.lt {
-		vi.Set(langID, FileVersion, fileVersion)
+		vi.Set(langID, key, value)
+	}
+}
+
+// deleteAll removes a key from every language.
+func (vi *Info) deleteAll(key string) {
+	for _, st := range vi.lt {
+		delete(*st, key)
 	}
 }
 
+// isSemverNumber tells if s is a numeric identifier, without leading zeros.
+func isSemverNumber(s string) bool {
+	if s == "" || s != "0" && s[0] == '0' {
+		return false
+	}
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// isValidSemverIdentifiers checks the dot separated identifiers of a pre-release or of build metadata.
+func isValidSemverIdentifiers(s string, prerelease bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, c := range id {
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '-') {
+				return false
+			}
+		}
+		if prerelease && strings.Trim(id, "0123456789") == "" && !isSemverNumber(id) {
+			return false
+		}
+	}
+	return true
+}
+
 // MergeTranslations merges several VERSIONINFO structs into one multilingual struct.
 //
 // The fixed part will be taken in priority from:
@@ -178,17 +417,26 @@ func MergeTranslations(translations map[uint16]*Info) *Info {
 		return vi
 	}
 	vi.Flags = main.Flags
+	vi.FlagsMask = main.FlagsMask
+	vi.OS = main.OS
 	vi.Type = main.Type
+	vi.Subtype = main.Subtype
 	vi.Timestamp = main.Timestamp
 	vi.ProductVersion = main.ProductVersion
 	vi.FileVersion = main.FileVersion
 
+	var (
+		merged   []Translation
+		explicit bool
+	)
 	for langID, trans := range translations {
-		st := trans.lt[langID]
+		src := langID
+		st := trans.lt[src]
 		if st == nil || len(*st) == 0 {
-			st = trans.lt[LangNeutral]
+			src = LangNeutral
+			st = trans.lt[src]
 			if st == nil || len(*st) == 0 {
-				st = trans.singleLang()
+				src, st = trans.singleLang()
 				if st == nil {
 					continue
 				}
@@ -197,28 +445,59 @@ func MergeTranslations(translations map[uint16]*Info) *Info {
 		for k, v := range *st {
 			vi.Set(langID, k, v)
 		}
+		vi.SetCodePage(langID, trans.CodePage(src))
+		if trans.translations == nil {
+			merged = appendTranslation(merged, Translation{LangID: langID, CodePage: vi.CodePage(langID)})
+			continue
+		}
+		explicit = true
+		for _, t := range trans.translations {
+			if t.LangID == langID || len(trans.lt) <= 1 {
+				merged = appendTranslation(merged, Translation{LangID: langID, CodePage: t.CodePage})
+			}
+		}
+	}
+
+	// The Translation array is only kept if one of the structs had a custom one
+	if explicit {
+		sort.Slice(merged, func(i, j int) bool {
+			return merged[i].LangID < merged[j].LangID ||
+				merged[i].LangID == merged[j].LangID && merged[i].CodePage < merged[j].CodePage
+		})
+		vi.translations = merged
 	}
 
 	return vi
 }
 
-func (vi *Info) singleLang() *stringTable {
+func appendTranslation(translations []Translation, t Translation) []Translation {
+	for i := range translations {
+		if translations[i] == t {
+			return translations
+		}
+	}
+	return append(translations, t)
+}
+
+func (vi *Info) singleLang() (uint16, *stringTable) {
 	var (
-		seen bool
-		lang *stringTable
+		seen   bool
+		langID uint16
+		lang   *stringTable
 	)
 
-	for _, st := range vi.lt {
+	for id, st := range vi.lt {
 		if st != nil && len(*st) != 0 {
 			if seen {
-				return nil
+				return 0, nil
 			}
 			seen = true
+			langID = id
 			lang = st
 		}
 	}
 
-	return lang
+	return langID, lang
 }
 
 func findMainTranslation(translations map[uint16]*Info) *Info {
@@ -297,7 +576,10 @@ func (vi *Info) splitLangs() map[uint16]*Info {
 			FileVersion:    vi.FileVersion,
 			ProductVersion: vi.ProductVersion,
 			Flags:          vi.Flags,
+			FlagsMask:      vi.FlagsMask,
+			OS:             vi.OS,
 			Type:           vi.Type,
+			Subtype:        vi.Subtype,
 			Timestamp:      vi.Timestamp,
 		}
 		for k, v := range defaults {
@@ -306,6 +588,12 @@ func (vi *Info) splitLangs() map[uint16]*Info {
 		for k, v := range *st {
 			trans.Set(langID, k, v)
 		}
+		trans.SetCodePage(langID, vi.CodePage(langID))
+		for _, t := range vi.translations {
+			if t.LangID == langID {
+				trans.translations = append(trans.translations, t)
+			}
+		}
 		m[langID] = trans
 	}
 
@@ -2,6 +2,8 @@ package version
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -170,16 +172,216 @@ func TestFromBytes_Empty(t *testing.T) {
 	}
 }
 
-func TestFromBytes_ErrCodePage(t *testing.T) {
+func TestFromBytes_CodePage(t *testing.T) {
 	b := loadGolden(t)
 	vi, err := FromBytes(b)
-	if err == nil || vi != nil {
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The neutral string table has code page 1201, but Translation says 1200
+	if vi.CodePage(0) != 0x04B1 || vi.Get(0, "Smile") != "😀" {
 		t.Fail()
-		return
 	}
-	if err.Error() != errUnhandledCodePage {
+	want := []Translation{{0, 1200}, {0x409, 1200}, {0x40C, 1200}}
+	if !reflect.DeepEqual(vi.Translations(), want) {
+		t.Error(vi.Translations())
+	}
+	if !bytes.Equal(b, vi.Bytes()) {
+		t.Error("round trip should not change anything")
+	}
+}
+
+func TestFromBytes_FixedFileInfo(t *testing.T) {
+	for _, ref := range []Info{
+		{Type: Driver, Subtype: DriverSound, OS: OSNT, FlagsMask: 0x17},
+		{Type: Font, Subtype: FontRaster, OS: OSDOSWindows16, FlagsMask: FlagsMaskNone},
+		{Type: VXD, Subtype: 0x1234, OS: OSUnknown},
+		{Type: StaticLib, OS: 0x50004},
+		{Type: DLL},
+	} {
+		ref.Set(0, ProductName, "Product")
+		vi, err := FromBytes(ref.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vi.Type != ref.Type || vi.Subtype != ref.Subtype || vi.OS != ref.OS || vi.FlagsMask != ref.FlagsMask {
+			t.Errorf("expected %v, got %v", ref, *vi)
+		}
+		if !bytes.Equal(vi.Bytes(), ref.Bytes()) {
+			t.Error("round trip should not change anything")
+		}
+	}
+
+	ffi := (&Info{OS: OSUnknown, FlagsMask: FlagsMaskNone}).fixedFileInfo()
+	if ffi.FileOS != 0 || ffi.FileFlagsMask != 0 {
+		t.Fail()
+	}
+	ffi = (&Info{}).fixedFileInfo()
+	if ffi.FileOS != 0x40004 || ffi.FileFlagsMask != 0x3F {
+		t.Fail()
+	}
+}
+
+// node16 builds a node of the legacy 16-bit VS_VERSIONINFO layout.
+func node16(key string, value []byte, children ...[]byte) []byte {
+	b := []byte{0, 0, byte(len(value)), byte(len(value) >> 8)}
+	b = append(b, key+"\x00"...)
+	pad := func() {
+		for len(b)&3 != 0 {
+			b = append(b, 0)
+		}
+	}
+	pad()
+	b = append(b, value...)
+	for _, c := range children {
+		pad()
+		b = append(b, c...)
+	}
+	b[0], b[1] = byte(len(b)), byte(len(b)>>8)
+	return b
+}
+
+func TestFromBytes_Legacy(t *testing.T) {
+	ref := &Info{
+		FileVersion:    [4]uint16{3, 1, 0, 103},
+		ProductVersion: [4]uint16{3, 1, 0, 0},
+		Flags:          versionFlags{Prerelease: true},
+	}
+	ref.Set(0x409, ProductName, "Café™")
+	ref.Set(0x409, Comments, "")
+	ref.Set(0x40C, ProductName, "Текст")
+	ref.SetCodePage(0x409, 1252)
+	ref.SetCodePage(0x40C, 1251)
+	fixed := &bytes.Buffer{}
+	binary.Write(fixed, binary.LittleEndian, ref.fixedFileInfo())
+
+	data := node16(vsVersionInfo, fixed.Bytes(),
+		node16(stringFileInfo, nil,
+			node16("040904E4", nil,
+				node16(ProductName, []byte("Caf\xe9\x99\x00")),
+				node16(Comments, nil),
+			),
+			node16("040C04E3", nil,
+				node16(ProductName, []byte("\xd2\xe5\xea\xf1\xf2\x00")),
+			),
+		),
+		node16(varFileInfo, nil,
+			node16(translation, []byte{0x09, 0x04, 0xE4, 0x04, 0x0C, 0x04, 0xE3, 0x04}),
+		),
+	)
+
+	vi, err := FromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi.translations != nil {
+		t.Error(vi.Translations())
+	}
+	if !bytes.Equal(vi.Bytes(), ref.Bytes()) {
+		t.Errorf("expected:\n%s\ngot:\n%s", hex.Dump(ref.Bytes()), hex.Dump(vi.Bytes()))
+	}
+
+	// Unknown code page
+	data = node16(vsVersionInfo, fixed.Bytes(),
+		node16(stringFileInfo, nil,
+			node16("041103A4", nil,
+				node16(ProductName, []byte("\x90\xbb\x95\x69\x00")),
+			),
+		),
+	)
+	vi, err = FromBytes(data)
+	if err == nil || err.Error() != errUnhandledCodePage || vi != nil {
+		t.Error(err)
+	}
+
+	// Truncated
+	for _, n := range []int{20, 40, 80, 90} {
+		if _, err = FromBytes(data[:n]); err == nil {
+			t.Errorf("%d: expected an error", n)
+		}
+	}
+}
+
+func TestInfo_SetTranslations(t *testing.T) {
+	vi := &Info{}
+	vi.Set(0x409, ProductName, "Product")
+	vi.Set(0x40C, ProductName, "Produit")
+	vi.SetCodePage(0x40C, 1252)
+	if !reflect.DeepEqual(vi.Translations(), []Translation{{0x409, 1200}, {0x40C, 1252}}) {
+		t.Error(vi.Translations())
+	}
+	if !bytes.Contains(vi.Bytes(), []byte("0\x004\x000\x00c\x000\x004\x00e\x004\x00")) {
+		t.Error("string table key should be 040c04e4")
+	}
+
+	vi2, err := FromBytes(vi.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vi2.translations != nil || vi2.CodePage(0x40C) != 1252 || !bytes.Equal(vi2.Bytes(), vi.Bytes()) {
+		t.Error("code page should be preserved")
+	}
+
+	custom := []Translation{{0x409, 1252}, {0x409, 1200}, {0x411, 932}}
+	vi.SetTranslations(custom)
+	custom[0].CodePage = 0
+	vi2, err = FromBytes(vi.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vi2.Translations(), []Translation{{0x409, 1252}, {0x409, 1200}, {0x411, 932}}) {
+		t.Error(vi2.Translations())
+	}
+	if !bytes.Equal(vi2.Bytes(), vi.Bytes()) {
+		t.Error("translations should be preserved")
+	}
+
+	vi.SetTranslations([]Translation{})
+	vi2, _ = FromBytes(vi.Bytes())
+	if vi2.translations == nil || len(vi2.Translations()) != 0 {
+		t.Error("empty translations should be preserved")
+	}
+
+	vi.SetTranslations(nil)
+	if !reflect.DeepEqual(vi.Translations(), []Translation{{0x409, 1200}, {0x40C, 1252}}) {
+		t.Error(vi.Translations())
+	}
+}
+
+func TestInfo_SplitTranslations_CodePage(t *testing.T) {
+	vi := &Info{}
+	vi.Set(0, ProductName, "Product")
+	vi.Set(0x40C, ProductName, "Produit")
+	vi.Set(0x411, ProductName, "製品")
+	vi.SetCodePage(0x40C, 1252)
+	vi.SetTranslations([]Translation{{0x40C, 1252}, {0x411, 932}})
+
+	split := vi.SplitTranslations()
+	if split[0x40C].CodePage(0x40C) != 1252 || !reflect.DeepEqual(split[0x411].Translations(), []Translation{{0x411, 932}}) {
 		t.Fail()
 	}
+	if !reflect.DeepEqual(split[0].Translations(), []Translation{{0, 1200}}) {
+		t.Error(split[0].Translations())
+	}
+
+	// Go through binary format, as winres does
+	for langID := range split {
+		split[langID], _ = FromBytes(split[langID].Bytes())
+	}
+	merged := MergeTranslations(split)
+	if merged.CodePage(0x40C) != 1252 || merged.CodePage(0x411) != 1200 {
+		t.Fail()
+	}
+	if !reflect.DeepEqual(merged.Translations(), []Translation{{0, 1200}, {0x40C, 1252}, {0x411, 932}}) {
+		t.Error(merged.Translations())
+	}
+
+	delete(split, 0x411)
+	merged = MergeTranslations(split)
+	if merged.translations != nil {
+		t.Error("default translations should not become custom")
+	}
 }
 
 func TestFromBytes_ErrEOF1(t *testing.T) {
@@ -402,6 +604,27 @@ func TestInfo_Set(t *testing.T) {
 	}
 }
 
+func TestInfo_Get(t *testing.T) {
+	vi := &Info{}
+	if vi.Get(0x409, ProductName) != "" || len(vi.LangIDs()) != 0 {
+		t.Fail()
+	}
+
+	vi.Set(0x40C, ProductName, "Bon Produit")
+	vi.Set(0x409, ProductName, "Good Product")
+	vi.Set(0, "Smile", "😀")
+
+	if vi.Get(0x409, ProductName) != "Good Product" || vi.Get(0x40C, ProductName) != "Bon Produit" {
+		t.Fail()
+	}
+	if vi.Get(0, "Smile") != "😀" || vi.Get(0, ProductName) != "" {
+		t.Fail()
+	}
+	if !reflect.DeepEqual(vi.LangIDs(), []uint16{0, 0x409, 0x40C}) {
+		t.Fail()
+	}
+}
+
 func TestInfo_SetFileVersion(t *testing.T) {
 	vi := &Info{}
 	// 0x409 is en-US, and the default language
@@ -474,6 +697,94 @@ func TestInfo_SetProductVersion_Empty(t *testing.T) {
 	checkBytes(t, vi)
 }
 
+func TestInfo_SetFromSemver(t *testing.T) {
+	tests := []struct {
+		v       string
+		build   uint16
+		version [4]uint16
+		flags   versionFlags
+		strings map[string]string
+		err     string
+	}{
+		{"1.4.0", 0, [4]uint16{1, 4, 0, 0}, versionFlags{}, map[string]string{
+			FileVersion:    "1.4.0.0",
+			ProductVersion: "1.4.0",
+		}, ""},
+		{"v1.4.0-rc.2+abc123", 17, [4]uint16{1, 4, 0, 17}, versionFlags{Prerelease: true, PrivateBuild: true, SpecialBuild: true}, map[string]string{
+			FileVersion:    "1.4.0.17",
+			ProductVersion: "1.4.0-rc.2+abc123",
+			SpecialBuild:   "rc.2",
+			PrivateBuild:   "abc123",
+		}, ""},
+		{"65535.0.65535-x-y.0+001.exp-sha", 65535, [4]uint16{65535, 0, 65535, 65535}, versionFlags{Prerelease: true, PrivateBuild: true, SpecialBuild: true}, map[string]string{
+			FileVersion:    "65535.0.65535.65535",
+			ProductVersion: "65535.0.65535-x-y.0+001.exp-sha",
+			SpecialBuild:   "x-y.0",
+			PrivateBuild:   "001.exp-sha",
+		}, ""},
+		{"1.65536.0", 0, [4]uint16{}, versionFlags{}, nil, errVersionTooBig},
+		{"99999999999999999999.0.0", 0, [4]uint16{}, versionFlags{}, nil, errVersionTooBig},
+		{"1.2", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"1.2.3.4", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"01.2.3", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"1.2.x", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"1.2.3-", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"1.2.3-rc..1", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"1.2.3-rc.01", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"1.2.3+a_b", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+		{"", 0, [4]uint16{}, versionFlags{}, nil, errInvalidSemver},
+	}
+	for _, tt := range tests {
+		vi := &Info{}
+		vi.Set(0x409, ProductName, "Product")
+		vi.Set(0x40C, ProductName, "Produit")
+		err := vi.SetFromSemver(tt.v, tt.build)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: expected error %q, got %v", tt.v, tt.err, err)
+			}
+			if vi.FileVersion != [4]uint16{} || len(*vi.lt[0x409]) != 1 {
+				t.Errorf("%q: Info should not change on error", tt.v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.v, err)
+			continue
+		}
+		if vi.FileVersion != tt.version || vi.ProductVersion != tt.version || vi.Flags != tt.flags {
+			t.Errorf("%q: got %v %v %v", tt.v, vi.FileVersion, vi.ProductVersion, vi.Flags)
+		}
+		for _, langID := range []uint16{0x409, 0x40C} {
+			if len(*vi.lt[langID]) != len(tt.strings)+1 {
+				t.Errorf("%q: got %v", tt.v, *vi.lt[langID])
+			}
+			for k, v := range tt.strings {
+				if vi.Get(langID, k) != v {
+					t.Errorf("%q: %s should be %q, got %q", tt.v, k, v, vi.Get(langID, k))
+				}
+			}
+		}
+	}
+
+	vi := &Info{}
+	vi.SetFromSemver("1.0.0-beta", 3)
+	if len(vi.lt) != 1 || vi.Get(LangNeutral, SpecialBuild) != "beta" {
+		t.Fail()
+	}
+
+	// A second call replaces everything
+	vi.SetFromSemver("1.0.0-rc.1+abc", 4)
+	vi.SetFromSemver("1.0.0", 5)
+	if vi.Flags != (versionFlags{}) || len(*vi.lt[LangNeutral]) != 2 || vi.Get(LangNeutral, ProductVersion) != "1.0.0" {
+		t.Errorf("%+v %v", vi.Flags, *vi.lt[LangNeutral])
+	}
+	vi.SetFromSemver("1.0.1+def", 6)
+	if vi.Flags != (versionFlags{PrivateBuild: true}) || vi.Get(LangNeutral, SpecialBuild) != "" || vi.Get(LangNeutral, PrivateBuild) != "def" {
+		t.Errorf("%+v %v", vi.Flags, *vi.lt[LangNeutral])
+	}
+}
+
 func TestInfo_SplitTranslations(t *testing.T) {
 	var vi *Info
 
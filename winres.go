@@ -6,6 +6,8 @@ import (
 	"errors"
 	"io"
 
+	"github.com/tc-hib/winres/compressed"
+	"github.com/tc-hib/winres/lcid"
 	"github.com/tc-hib/winres/version"
 )
 
@@ -33,6 +35,13 @@ const (
 	RT_MANIFEST     ID = 24
 )
 
+// Manifest IDs from https://learn.microsoft.com/en-us/windows/win32/sbscs/using-side-by-side-assemblies-as-a-resource
+const (
+	CREATEPROCESS_MANIFEST_RESOURCE_ID                 ID = 1 // Manifest of an executable
+	ISOLATIONAWARE_MANIFEST_RESOURCE_ID                ID = 2 // Manifest of a DLL, loaded with the DLL
+	ISOLATIONAWARE_NOSTATICIMPORT_MANIFEST_RESOURCE_ID ID = 3 // Manifest of a DLL, not used for its static imports
+)
+
 const (
 	LCIDNeutral = 0
 	LCIDDefault = 0x409 // en-US is default
@@ -89,19 +98,201 @@ func (rs *ResourceSet) Set(typeID, resID Identifier, langID uint16, data []byte)
 	return nil
 }
 
+// SetReader adds or replaces a resource whose data is read from a source only when needed,
+// such as a large file that should not be loaded into memory.
+//
+// typeID, resID and langID are the same as in Set.
+//
+// size is the exact size of the data, and open is called every time the data is needed, for example by Get,
+// or once or twice by WriteObject and WriteToEXE, which stream the data.
+// So open must return a new reader, positioned at the beginning of the data.
+// Reading a different number of bytes than size is an error.
+//
+// Like with Set, passing a nil open function deletes the resource.
+func (rs *ResourceSet) SetReader(typeID, resID Identifier, langID uint16, size int64, open func() (io.ReadCloser, error)) error {
+	if err := checkIdentifier(resID); err != nil {
+		return err
+	}
+	if err := checkIdentifier(typeID); err != nil {
+		return err
+	}
+	if size < 0 {
+		return errors.New(errNegativeDataSize)
+	}
+	if size > maxDataSize {
+		return errors.New(errDataSizeTooBig)
+	}
+
+	if open == nil {
+		rs.delete(typeID, resID, langID)
+		return nil
+	}
+	rs.setEntry(typeID, resID, langID, DataEntry{size: size, open: open})
+
+	return nil
+}
+
+// SetCompressed adds or replaces a resource, compressing its data with the given method.
+//
+// The data starts with a small header, so that the compressed package can load it back at run time.
+// Only RT_RCDATA and custom types may be compressed, since Windows would not understand compressed standard resources.
+func (rs *ResourceSet) SetCompressed(typeID, resID Identifier, langID uint16, data []byte, method compressed.Method) error {
+	if id, ok := typeID.(ID); ok && id != RT_RCDATA && typeNames[id] != "" {
+		return errors.New(errCannotCompressType)
+	}
+	data, err := compressed.Encode(data, method)
+	if err != nil {
+		return err
+	}
+	return rs.Set(typeID, resID, langID, data)
+}
+
 // SetVersionInfo sets the VersionInfo structure.
 //
 // This what Windows displays in the Details tab of file properties.
+//
+// If vi describes a DLL, a manifest that has CREATEPROCESS_MANIFEST_RESOURCE_ID is moved to
+// ISOLATIONAWARE_MANIFEST_RESOURCE_ID, as it would be ignored in a DLL.
 func (rs *ResourceSet) SetVersionInfo(vi version.Info) {
 	for langID, res := range vi.SplitTranslations() {
 		rs.Set(RT_VERSION, ID(1), langID, res.Bytes())
 	}
+	rs.updateManifestID()
+}
+
+// GetVersionInfo returns the VersionInfo structure, with the translations of every language merged into one.
+//
+// See version.MergeTranslations.
+func (rs *ResourceSet) GetVersionInfo() (*version.Info, error) {
+	_, vi, found, err := rs.getVersionInfo()
+	if err == nil && !found {
+		err = errors.New(errNoVersionInfo)
+	}
+	return vi, err
+}
+
+// UpdateVersionInfo modifies the VersionInfo structure in place.
+//
+// The callback receives the merged translations, as returned by GetVersionInfo,
+// or an empty structure if there is none.
+// Then every translation is written back, and languages that are not in the structure anymore are removed.
+func (rs *ResourceSet) UpdateVersionInfo(f func(vi *version.Info)) error {
+	resID, vi, found, err := rs.getVersionInfo()
+	if err != nil {
+		return err
+	}
+	if !found {
+		vi = &version.Info{}
+	}
+
+	f(vi)
+
+	rs.deleteResource(RT_VERSION, resID)
+	for langID, res := range vi.SplitTranslations() {
+		rs.set(RT_VERSION, resID, langID, res.Bytes())
+	}
+	rs.updateManifestID()
+	return nil
+}
+
+// getVersionInfo merges the translations of the first VERSIONINFO resource.
+//
+// It also returns the resource ID, which is 1 when there is no VERSIONINFO, and whether there is one.
+func (rs *ResourceSet) getVersionInfo() (Identifier, *version.Info, bool, error) {
+	var (
+		resID        Identifier = ID(1)
+		found        bool
+		err          error
+		translations = map[uint16]*version.Info{}
+	)
+	rs.WalkType(RT_VERSION, func(id Identifier, langID uint16, data []byte) bool {
+		if found && id != resID {
+			return false
+		}
+		resID, found = id, true
+		translations[langID], err = version.FromBytes(data)
+		return err == nil
+	})
+	if err != nil {
+		return resID, nil, found, err
+	}
+	if !found {
+		return resID, nil, false, nil
+	}
+	return resID, version.MergeTranslations(translations), true, nil
 }
 
 // SetManifest is a simplified way to embed a typical application manifest,
 // without writing xml directly.
+//
+// The manifest ID is CREATEPROCESS_MANIFEST_RESOURCE_ID, unless the resource set contains
+// a VERSIONINFO for a DLL, in which case it is ISOLATIONAWARE_MANIFEST_RESOURCE_ID.
+// The VERSIONINFO may be set before or after the manifest.
+// Use SetManifestWithID to choose another ID.
 func (rs *ResourceSet) SetManifest(manifest AppManifest) {
-	rs.Set(RT_MANIFEST, ID(1), LCIDDefault, makeManifest(manifest))
+	rs.Set(RT_MANIFEST, rs.defaultManifestID(), LCIDDefault, makeManifest(manifest))
+}
+
+// SetManifestWithID is like SetManifest, with an explicit manifest ID.
+//
+// Windows only reads these IDs:
+//
+//	CREATEPROCESS_MANIFEST_RESOURCE_ID:                 for an executable
+//	ISOLATIONAWARE_MANIFEST_RESOURCE_ID:                for a DLL
+//	ISOLATIONAWARE_NOSTATICIMPORT_MANIFEST_RESOURCE_ID: for a DLL, when its static imports must not use the manifest
+func (rs *ResourceSet) SetManifestWithID(id ID, manifest AppManifest) error {
+	return rs.Set(RT_MANIFEST, id, LCIDDefault, makeManifest(manifest))
+}
+
+// defaultManifestID returns the manifest ID matching the file type found in VERSIONINFO.
+func (rs *ResourceSet) defaultManifestID() ID {
+	id := CREATEPROCESS_MANIFEST_RESOURCE_ID
+	rs.WalkType(RT_VERSION, func(_ Identifier, _ uint16, data []byte) bool {
+		if vi, err := version.FromBytes(data); err == nil && vi.Type == version.DLL {
+			id = ISOLATIONAWARE_MANIFEST_RESOURCE_ID
+		}
+		return false
+	})
+	return id
+}
+
+// updateManifestID moves the manifest from CREATEPROCESS_MANIFEST_RESOURCE_ID to ISOLATIONAWARE_MANIFEST_RESOURCE_ID
+// when VERSIONINFO describes a DLL, so that the order of SetManifest and SetVersionInfo does not matter.
+func (rs *ResourceSet) updateManifestID() {
+	te := rs.Types[RT_MANIFEST]
+	if te == nil || rs.defaultManifestID() != ISOLATIONAWARE_MANIFEST_RESOURCE_ID {
+		return
+	}
+	re := te.Resources[CREATEPROCESS_MANIFEST_RESOURCE_ID]
+	if re == nil || te.Resources[ISOLATIONAWARE_MANIFEST_RESOURCE_ID] != nil {
+		return
+	}
+	for langID, e := range re.Data {
+		rs.setEntry(RT_MANIFEST, ISOLATIONAWARE_MANIFEST_RESOURCE_ID, uint16(langID), *e)
+	}
+	rs.deleteResource(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID)
+}
+
+// GetManifestDocument returns the first manifest of the resource set, as a Manifest document.
+//
+// Use it with SetManifestDocument to change a setting in a manifest without losing unknown data.
+func (rs *ResourceSet) GetManifestDocument() (*Manifest, error) {
+	var data []byte
+	rs.WalkType(RT_MANIFEST, func(_ Identifier, _ uint16, d []byte) bool {
+		data = d
+		return false
+	})
+	if data == nil {
+		return nil, errors.New(errNoManifest)
+	}
+	return ParseManifest(data)
+}
+
+// SetManifestDocument embeds a Manifest document as the application manifest.
+//
+// The manifest ID is chosen as in SetManifest.
+func (rs *ResourceSet) SetManifestDocument(manifest *Manifest) {
+	rs.Set(RT_MANIFEST, rs.defaultManifestID(), LCIDDefault, manifest.Bytes())
 }
 
 // WriteObject writes a full object file into w.
@@ -109,6 +300,15 @@ func (rs *ResourceSet) WriteObject(w io.Writer, arch Arch) error {
 	return writeObject(w, rs, arch)
 }
 
+// WriteDLL writes a resource-only DLL into w.
+//
+// The DLL has no code and no entry point, only a .rsrc section.
+// It can be loaded with LoadLibraryEx and LOAD_LIBRARY_AS_DATAFILE or LOAD_LIBRARY_AS_IMAGE_RESOURCE,
+// and is suitable for icon libraries, satellite DLLs, and .mui files.
+func (rs *ResourceSet) WriteDLL(w io.Writer, arch Arch, opts DLLOptions) error {
+	return writeDLL(w, rs, arch, opts)
+}
+
 // Count returns the number of resources in the set.
 func (rs *ResourceSet) Count() int {
 	return rs.numDataEntries()
@@ -127,7 +327,7 @@ func (rs *ResourceSet) Walk(f func(typeID, resID Identifier, langID uint16, data
 		for _, rk := range te.OrderedKeys {
 			re := te.Resources[rk]
 			for _, dk := range re.OrderedKeys {
-				if !f(tk, rk, uint16(dk), re.Data[dk].Data) {
+				if !f(tk, rk, uint16(dk), re.Data[dk].bytes()) {
 					return
 				}
 			}
@@ -149,7 +349,7 @@ func (rs *ResourceSet) WalkType(typeID Identifier, f func(resID Identifier, lang
 	for _, rk := range te.OrderedKeys {
 		re := te.Resources[rk]
 		for _, dk := range re.OrderedKeys {
-			if !f(rk, uint16(dk), re.Data[dk].Data) {
+			if !f(rk, uint16(dk), re.Data[dk].bytes()) {
 				return
 			}
 		}
@@ -175,21 +375,65 @@ func (rs *ResourceSet) Get(typeID, resID Identifier, langID uint16) []byte {
 		return nil
 	}
 
-	return de.Data
+	return de.bytes()
 }
 
-// set is the only function that may create/modify entries in the ResourceSet
-func (rs *ResourceSet) set(typeID Identifier, resID Identifier, langID uint16, data []byte) {
-	if rs.Types == nil {
-		rs.Types = make(map[Identifier]*TypeEntry)
+// Find returns the translation of a resource that Windows would load for a list of preferred languages,
+// and its language ID.
+//
+// preferred usually starts with the language of the thread, followed by the user default language,
+// then the system default language.
+//
+// For each preferred language, Find looks for that exact language,
+// then the same primary language with the neutral sublanguage (such as 0x000C for 0x040C).
+// Then it looks for en-US, then LANG_NEUTRAL, and finally for any language, as FindResourceEx does.
+//
+// Returns nil if the resource was not found.
+func (rs *ResourceSet) Find(typeID, resID Identifier, preferred ...uint16) ([]byte, uint16) {
+	te := rs.Types[typeID]
+	if te == nil {
+		return nil, 0
+	}
+	re := te.Resources[resID]
+	if re == nil || len(re.Data) == 0 {
+		return nil, 0
+	}
+
+	candidates := make([]uint16, 0, len(preferred)*2+2)
+	for _, langID := range preferred {
+		candidates = append(candidates, langID)
+		if langID != LCIDNeutral {
+			candidates = append(candidates, lcid.MakeLangID(lcid.PrimaryLangID(langID), lcid.SubLangNeutral))
+		}
+	}
+	candidates = append(candidates, LCIDDefault, LCIDNeutral)
+
+	for _, langID := range candidates {
+		if de := re.Data[ID(langID)]; de != nil {
+			return de.bytes(), langID
+		}
 	}
 
+	langID := rs.firstLang(typeID, resID)
+	return re.Data[ID(langID)].bytes(), langID
+}
+
+func (rs *ResourceSet) set(typeID Identifier, resID Identifier, langID uint16, data []byte) {
 	if data == nil {
 		// Like UpdateResource, delete resources by passing nil
 		rs.delete(typeID, resID, langID)
 		return
 	}
 
+	rs.setEntry(typeID, resID, langID, DataEntry{Data: data})
+}
+
+// setEntry is the only function that may create/modify entries in the ResourceSet
+func (rs *ResourceSet) setEntry(typeID Identifier, resID Identifier, langID uint16, entry DataEntry) {
+	if rs.Types == nil {
+		rs.Types = make(map[Identifier]*TypeEntry)
+	}
+
 	te := rs.Types[typeID]
 	if te == nil {
 		te = &TypeEntry{
@@ -219,12 +463,14 @@ func (rs *ResourceSet) set(typeID Identifier, resID Identifier, langID uint16, d
 
 	de := re.Data[ID(langID)]
 	if de == nil {
+		// The type entry must be ordered again, so that it orders the resource entry
+		te.OrderedKeys = nil
 		re.OrderedKeys = nil
 		de = &DataEntry{}
 		re.Data[ID(langID)] = de
 	}
 
-	de.Data = data
+	*de = entry
 }
 
 func (rs *ResourceSet) delete(typeID Identifier, resID Identifier, langID uint16) {
@@ -239,6 +485,7 @@ func (rs *ResourceSet) delete(typeID Identifier, resID Identifier, langID uint16
 	}
 
 	delete(re.Data, ID(langID))
+	te.OrderedKeys = nil
 	re.OrderedKeys = nil
 
 	if len(re.Data) > 0 {
@@ -255,6 +502,27 @@ func (rs *ResourceSet) delete(typeID Identifier, resID Identifier, langID uint16
 	delete(rs.Types, typeID)
 }
 
+// deleteResource deletes a resource in every language.
+func (rs *ResourceSet) deleteResource(typeID Identifier, resID Identifier) {
+	te := rs.Types[typeID]
+	if te == nil {
+		return
+	}
+
+	if _, ok := te.Resources[resID]; !ok {
+		return
+	}
+
+	delete(te.Resources, resID)
+	te.OrderedKeys = nil
+
+	if len(te.Resources) > 0 {
+		return
+	}
+
+	delete(rs.Types, typeID)
+}
+
 // firstLang finds the first language ID of a resource.
 //
 // When an icon image has several languages, Windows takes the first one
@@ -297,6 +565,44 @@ func LoadFromEXESingleType(exe io.ReadSeeker, typeID Identifier) (*ResourceSet,
 	return loadFromEXE(exe, typeID)
 }
 
+// ReadVersionInfo loads the VersionInfo structure of an executable, with all its translations merged.
+func ReadVersionInfo(exe io.ReadSeeker) (*version.Info, error) {
+	rs, err := LoadFromEXESingleType(exe, RT_VERSION)
+	if err != nil {
+		return nil, err
+	}
+	return rs.GetVersionInfo()
+}
+
+// LoadFromEXELazy loads the resource directory of an executable, but not the actual resource data.
+//
+// size is the size of the executable.
+//
+// Data is read from exe on demand, when a method such as Get or Walk needs it,
+// and WriteObject or WriteToEXE stream it from exe instead of loading it into memory.
+// So exe must remain readable as long as the ResourceSet is used.
+//
+// Data entries of a lazy ResourceSet have a nil Data field until they are set again.
+func LoadFromEXELazy(exe io.ReaderAt, size int64) (*ResourceSet, error) {
+	rs := &ResourceSet{}
+
+	sec, err := findRSRCSection(io.NewSectionReader(exe, 0, size))
+	if err != nil {
+		if err == ErrNoResources {
+			return rs, err
+		}
+		return nil, err
+	}
+
+	section := io.NewSectionReader(exe, int64(sec.PointerToRawData), int64(sec.SizeOfRawData))
+	err = rs.readSection(section, sec.VirtualAddress, ID(0), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
 func loadFromEXE(exe io.ReadSeeker, typeID Identifier) (*ResourceSet, error) {
 	rs := &ResourceSet{}
 
@@ -316,12 +622,20 @@ func loadFromEXE(exe io.ReadSeeker, typeID Identifier) (*ResourceSet, error) {
 	return rs, nil
 }
 
-func (rs *ResourceSet) bytes() ([]byte, []int) {
+// directory returns the content of the rsrc section, except the actual data.
+func (rs *ResourceSet) directory() *rsrcContent {
 	buf := bytes.Buffer{}
-	// ResourceSet.write may only fail on io.Write() calls.
+	// ResourceSet.writeDirectory may only fail on io.Write() calls.
 	// bytes.Buffer.Write never returns an error.
-	reloc, _ := rs.write(&buf)
-	return buf.Bytes(), reloc
+	s, _ := rs.writeDirectory(&buf)
+	return &rsrcContent{
+		dir:   buf.Bytes(),
+		reloc: s.relocAddr,
+		size:  uint32(rs.fullSize()),
+		write: func(w io.Writer) error {
+			return rs.writeData(w, s)
+		},
+	}
 }
 
 // WriteToEXE patches an executable to replace its resources with this ResourceSet.
@@ -336,12 +650,11 @@ func (rs *ResourceSet) bytes() ([]byte, []int) {
 //  WithAuthenticode(<how>) // Allows updating the .rsrc section despite the file being signed
 //
 func (rs *ResourceSet) WriteToEXE(dst io.Writer, src io.ReadSeeker, opt ...exeOption) error {
-	data, reloc := rs.bytes()
 	options := exeOptions{}
 	for _, o := range opt {
 		o(&options)
 	}
-	return replaceRSRCSection(dst, src, data, reloc, options)
+	return replaceRSRCSection(dst, src, rs.directory(), options)
 }
 
 // IsSignedEXE helps knowing if an exe file is signed before encountering an error with WriteToEXE.
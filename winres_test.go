@@ -9,9 +9,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/tc-hib/winres/compressed"
 	"github.com/tc-hib/winres/version"
 )
 
@@ -177,6 +179,67 @@ func TestResourceSet_SetManifest(t *testing.T) {
 	checkResourceSet(t, rs, ArchARM64)
 }
 
+func TestResourceSet_SetManifest_DLL(t *testing.T) {
+	rs := &ResourceSet{}
+	vi := version.Info{Type: version.DLL}
+	vi.Set(0x409, version.ProductName, "Library")
+	rs.SetVersionInfo(vi)
+	rs.SetManifest(AppManifest{})
+	if rs.Get(RT_MANIFEST, ISOLATIONAWARE_MANIFEST_RESOURCE_ID, LCIDDefault) == nil || rs.Get(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID, LCIDDefault) != nil {
+		t.Error("a DLL manifest should have ID 2")
+	}
+
+	rs = &ResourceSet{}
+	vi.Type = version.App
+	rs.SetVersionInfo(vi)
+	rs.SetManifestDocument(NewManifest(AppManifest{}))
+	if rs.Get(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID, LCIDDefault) == nil {
+		t.Error("an executable manifest should have ID 1")
+	}
+}
+
+func TestResourceSet_SetManifest_DLLAfter(t *testing.T) {
+	vi := version.Info{Type: version.DLL}
+	vi.Set(0x409, version.ProductName, "Library")
+	rs := &ResourceSet{}
+	rs.SetManifest(AppManifest{})
+	rs.SetVersionInfo(vi)
+	if rs.Get(RT_MANIFEST, ISOLATIONAWARE_MANIFEST_RESOURCE_ID, LCIDDefault) == nil || rs.Get(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID, LCIDDefault) != nil {
+		t.Error("a DLL manifest should be moved to ID 2")
+	}
+
+	// An existing ID 2 is not replaced
+	rs = &ResourceSet{}
+	rs.SetManifest(AppManifest{})
+	rs.SetManifestWithID(ISOLATIONAWARE_MANIFEST_RESOURCE_ID, AppManifest{LongPathAware: true})
+	rs.UpdateVersionInfo(func(vi *version.Info) { vi.Type = version.DLL })
+	if !bytes.Equal(rs.Get(RT_MANIFEST, ISOLATIONAWARE_MANIFEST_RESOURCE_ID, LCIDDefault), makeManifest(AppManifest{LongPathAware: true})) ||
+		rs.Get(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID, LCIDDefault) == nil {
+		t.Fail()
+	}
+
+	rs = &ResourceSet{}
+	rs.SetManifest(AppManifest{})
+	vi.Type = version.App
+	rs.SetVersionInfo(vi)
+	if rs.Get(RT_MANIFEST, CREATEPROCESS_MANIFEST_RESOURCE_ID, LCIDDefault) == nil {
+		t.Error("an executable manifest should keep ID 1")
+	}
+}
+
+func TestResourceSet_SetManifestWithID(t *testing.T) {
+	rs := &ResourceSet{}
+	if err := rs.SetManifestWithID(ISOLATIONAWARE_NOSTATICIMPORT_MANIFEST_RESOURCE_ID, AppManifest{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rs.Get(RT_MANIFEST, ID(3), LCIDDefault), makeManifest(AppManifest{})) || rs.Count() != 1 {
+		t.Fail()
+	}
+	if err := rs.SetManifestWithID(0, AppManifest{}); !isErr(err, errZeroID) {
+		t.Error(err)
+	}
+}
+
 func TestResourceSet_SetVersionInfo(t *testing.T) {
 	rs := &ResourceSet{}
 	vi := version.Info{}
@@ -188,6 +251,89 @@ func TestResourceSet_SetVersionInfo(t *testing.T) {
 	checkResourceSet(t, rs, ArchAMD64)
 }
 
+func TestResourceSet_GetVersionInfo(t *testing.T) {
+	rs := &ResourceSet{}
+	if vi, err := rs.GetVersionInfo(); vi != nil || !isErr(err, errNoVersionInfo) {
+		t.Error(err)
+	}
+
+	vi := version.Info{}
+	vi.FileVersion = [4]uint16{1, 2, 3, 4}
+	vi.Set(0x0409, version.ProductName, "Good product")
+	vi.Set(0x040C, version.ProductName, "Bon produit")
+	rs.SetVersionInfo(vi)
+	// Another resource ID is ignored
+	rs.Set(RT_VERSION, ID(2), 0x0411, []byte{1, 2, 3})
+
+	got, err := rs.GetVersionInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FileVersion != vi.FileVersion || !reflect.DeepEqual(got.LangIDs(), []uint16{0x409, 0x40C}) ||
+		got.Get(0x40C, version.ProductName) != "Bon produit" {
+		t.Fail()
+	}
+
+	rs.Set(RT_VERSION, ID(1), 0x0407, []byte{1, 2, 3})
+	if _, err = rs.GetVersionInfo(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestResourceSet_UpdateVersionInfo(t *testing.T) {
+	rs := &ResourceSet{}
+	err := rs.UpdateVersionInfo(func(vi *version.Info) {
+		vi.Set(0x0409, version.ProductName, "Good product")
+		vi.Set(0x040C, version.ProductName, "Bon produit")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Count() != 2 || rs.Get(RT_VERSION, ID(1), 0x40C) == nil {
+		t.Fail()
+	}
+
+	// Languages that disappear are removed, and the resource ID is kept
+	rs = &ResourceSet{}
+	vi := version.Info{}
+	vi.Set(0x0409, version.ProductName, "Good product")
+	vi.Set(0x040C, version.ProductName, "Bon produit")
+	vi.Set(0x0411, version.ProductName, "良い製品")
+	for langID, res := range vi.SplitTranslations() {
+		rs.Set(RT_VERSION, Name("VERSION"), langID, res.Bytes())
+	}
+	err = rs.UpdateVersionInfo(func(vi *version.Info) {
+		vi.SetProductVersion("1.2.3")
+		vi2 := version.Info{ProductVersion: vi.ProductVersion}
+		vi2.Set(0x0409, version.ProductName, vi.Get(0x0409, version.ProductName))
+		vi2.Set(0x0411, version.ProductName, vi.Get(0x0411, version.ProductName))
+		vi2.SetProductVersion("1.2.3")
+		*vi = vi2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Count() != 2 || rs.Get(RT_VERSION, Name("VERSION"), 0x40C) != nil || rs.Get(RT_VERSION, ID(1), 0x409) != nil {
+		t.Fail()
+	}
+	got, _ := rs.GetVersionInfo()
+	if got.ProductVersion != [4]uint16{1, 2, 3, 0} || got.Get(0x0411, version.ProductVersion) != "1.2.3" {
+		t.Fail()
+	}
+
+	rs.Set(RT_VERSION, Name("VERSION"), 0x0407, []byte{1, 2, 3})
+	called := false
+	if err = rs.UpdateVersionInfo(func(*version.Info) { called = true }); err == nil || called {
+		t.Fail()
+	}
+}
+
+func TestReadVersionInfo_Err(t *testing.T) {
+	if _, err := ReadVersionInfo(bytes.NewReader([]byte("MZ"))); err == nil {
+		t.Fail()
+	}
+}
+
 func TestResourceSet_Walk(t *testing.T) {
 	rs := ResourceSet{}
 	b := &bytes.Buffer{}
@@ -308,6 +454,51 @@ const manifest1 = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
 </assembly>
 `
 
+func TestResourceSet_Find(t *testing.T) {
+	newSet := func(langIDs ...uint16) *ResourceSet {
+		rs := &ResourceSet{}
+		for _, langID := range langIDs {
+			rs.Set(RT_RCDATA, ID(1), langID, []byte(fmt.Sprintf("%04X", langID)))
+		}
+		return rs
+	}
+
+	tests := []struct {
+		name      string
+		langIDs   []uint16
+		preferred []uint16
+		want      uint16
+	}{
+		{"exact", []uint16{0, 0x409, 0x40C, 0xC0C}, []uint16{0xC0C}, 0xC0C},
+		{"primary neutral", []uint16{0, 0x409, 0x00C, 0x40C}, []uint16{0xC0C}, 0x00C},
+		{"user default", []uint16{0, 0x407, 0x409, 0x40C}, []uint16{0xC0C, 0x407}, 0x407},
+		{"user default primary", []uint16{0, 0x007, 0x409}, []uint16{0xC0C, 0x807}, 0x007},
+		{"system default", []uint16{0, 0x409, 0x410}, []uint16{0xC0C, 0x407, 0x410}, 0x410},
+		{"en-US", []uint16{0, 0x409, 0x809}, []uint16{0xC0C, 0x407}, 0x409},
+		{"en-US before other English", []uint16{0x009, 0x809, 0x409}, []uint16{0x40C}, 0x409},
+		{"neutral", []uint16{0, 0x407, 0x809}, []uint16{0xC0C}, 0},
+		{"neutral preferred", []uint16{0, 0x409}, []uint16{0}, 0},
+		{"any", []uint16{0x411, 0x407, 0x809}, []uint16{0xC0C}, 0x407},
+		{"no preference", []uint16{0x411, 0x407}, nil, 0x407},
+		{"no preference en-US", []uint16{0x40C, 0x409}, nil, 0x409},
+	}
+	for _, tt := range tests {
+		rs := newSet(tt.langIDs...)
+		data, langID := rs.Find(RT_RCDATA, ID(1), tt.preferred...)
+		if langID != tt.want || string(data) != fmt.Sprintf("%04X", tt.want) {
+			t.Errorf("%s: expected %04X, got %04X %q", tt.name, tt.want, langID, data)
+		}
+	}
+
+	rs := newSet(0x409)
+	if data, langID := rs.Find(RT_RCDATA, ID(2), 0x409); data != nil || langID != 0 {
+		t.Fail()
+	}
+	if data, langID := rs.Find(RT_ICON, ID(1), 0x409); data != nil || langID != 0 {
+		t.Fail()
+	}
+}
+
 func Test_ResourceSet_set(t *testing.T) {
 	rs := ResourceSet{}
 	rs.set(ID(1), ID(2), 1, nil)
@@ -1060,3 +1251,216 @@ func (ws *writeSeeker) Seek(off int64, whence int) (int64, error) {
 func (ws writeSeeker) Bytes() []byte {
 	return ws.buf.Bytes()
 }
+
+type failingReaderAt struct {
+	r    io.ReaderAt
+	fail bool
+}
+
+func (r *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if r.fail {
+		return 0, errors.New(errRead)
+	}
+	return r.r.ReadAt(p, off)
+}
+
+func TestLoadFromEXELazy(t *testing.T) {
+	rs := &ResourceSet{}
+	rs.SetIcon(ID(1), newTestIcon(t, 32, 16))
+	rs.SetManifest(AppManifest{})
+	rs.Set(RT_RCDATA, Name("BIG"), 0, bytes.Repeat([]byte{1, 2, 3}, 100000))
+	rs.Set(RT_RCDATA, ID(1), 0x40C, []byte("données"))
+
+	dll := &bytes.Buffer{}
+	if err := rs.WriteDLL(dll, ArchAMD64, DLLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	src := &failingReaderAt{r: bytes.NewReader(dll.Bytes())}
+
+	lazy, err := LoadFromEXELazy(src, int64(dll.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lazy.Types[RT_RCDATA].Resources[Name("BIG")].Data[0].Data != nil {
+		t.Error("data should not be loaded")
+	}
+	if d := Diff(rs, lazy); d != nil {
+		t.Error(d)
+	}
+	if string(lazy.Get(RT_RCDATA, ID(1), 0x40C)) != "données" {
+		t.Fail()
+	}
+
+	expected := &bytes.Buffer{}
+	rs.WriteObject(expected, ArchI386)
+	obj := &bytes.Buffer{}
+	if err = lazy.WriteObject(obj, ArchI386); err != nil || !bytes.Equal(obj.Bytes(), expected.Bytes()) {
+		t.Error("object files differ", err)
+	}
+
+	exe := &bytes.Buffer{}
+	if err = lazy.WriteToEXE(exe, bytes.NewReader(dll.Bytes()), ForceCheckSum()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(exe.Bytes(), dll.Bytes()) {
+		t.Error("images differ")
+	}
+
+	// Setting a lazy entry replaces it
+	lazy.Set(RT_RCDATA, Name("BIG"), 0, []byte("small"))
+	if string(lazy.Get(RT_RCDATA, Name("BIG"), 0)) != "small" {
+		t.Fail()
+	}
+
+	// Read errors
+	src.fail = true
+	if lazy.Get(RT_RCDATA, ID(1), 0x40C) != nil {
+		t.Fail()
+	}
+	if err = lazy.WriteObject(&bytes.Buffer{}, ArchI386); err == nil || err.Error() != errRead {
+		t.Error(err)
+	}
+	if err = lazy.WriteToEXE(&bytes.Buffer{}, bytes.NewReader(dll.Bytes())); err == nil || err.Error() != errRead {
+		t.Error(err)
+	}
+	if err = lazy.WriteDLL(&bytes.Buffer{}, ArchAMD64, DLLOptions{}); err == nil || err.Error() != errRead {
+		t.Error(err)
+	}
+}
+
+func TestLoadFromEXELazy_Err(t *testing.T) {
+	b := []byte{'N', 'Z', 0x40: 0}
+	rs, err := LoadFromEXELazy(bytes.NewReader(b), int64(len(b)))
+	if err == nil || rs != nil || err.Error() != errNotPEImage {
+		t.Error(err)
+	}
+
+	dll := &bytes.Buffer{}
+	(&ResourceSet{}).WriteDLL(dll, ArchAMD64, DLLOptions{})
+	rs, err = LoadFromEXELazy(bytes.NewReader(dll.Bytes()), 0x300)
+	if err == nil || rs != nil || err.Error() != errSectionTooFar {
+		t.Error(err)
+	}
+}
+
+func TestResourceSet_SetReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	data := bytes.Repeat([]byte("asset"), 10001)
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	opened := 0
+	open := func() (io.ReadCloser, error) {
+		opened++
+		return os.Open(path)
+	}
+
+	rs := &ResourceSet{}
+	expected := &ResourceSet{}
+	for _, r := range []*ResourceSet{rs, expected} {
+		r.Set(RT_RCDATA, ID(1), 0, []byte("small"))
+		r.Set(RT_RCDATA, ID(3), 0, []byte("end"))
+	}
+	if err := rs.SetReader(RT_RCDATA, ID(2), 0x409, int64(len(data)), open); err != nil {
+		t.Fatal(err)
+	}
+	expected.Set(RT_RCDATA, ID(2), 0x409, data)
+	if rs.Types[RT_RCDATA].Resources[ID(2)].Data[0x409].Data != nil || opened != 0 {
+		t.Error("data should not be loaded")
+	}
+
+	obj, expectedObj := &bytes.Buffer{}, &bytes.Buffer{}
+	expected.WriteObject(expectedObj, ArchAMD64)
+	if err := rs.WriteObject(obj, ArchAMD64); err != nil || !bytes.Equal(obj.Bytes(), expectedObj.Bytes()) || opened != 1 {
+		t.Error("object files differ", err, opened)
+	}
+	if !bytes.Equal(rs.Get(RT_RCDATA, ID(2), 0x409), data) || rs.Count() != 3 {
+		t.Fail()
+	}
+
+	// A nil open function deletes the resource, like a nil slice in Set
+	if err := rs.SetReader(RT_RCDATA, ID(2), 0x409, 0, nil); err != nil || rs.Get(RT_RCDATA, ID(2), 0x409) != nil || rs.Count() != 2 {
+		t.Error(err)
+	}
+}
+
+func TestResourceSet_SetReader_Err(t *testing.T) {
+	open := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader([]byte("data"))), nil
+	}
+	rs := &ResourceSet{}
+	if err := rs.SetReader(RT_RCDATA, ID(0), 0, 4, open); !isErr(err, errZeroID) {
+		t.Error(err)
+	}
+	if err := rs.SetReader(Name(""), ID(1), 0, 4, open); !isErr(err, errEmptyName) {
+		t.Error(err)
+	}
+	if err := rs.SetReader(RT_RCDATA, ID(1), 0, -1, open); !isErr(err, errNegativeDataSize) {
+		t.Error(err)
+	}
+	if err := rs.SetReader(RT_RCDATA, ID(1), 0, 1<<31, open); !isErr(err, errDataSizeTooBig) {
+		t.Error(err)
+	}
+	if rs.Count() != 0 {
+		t.Fail()
+	}
+
+	for _, size := range []int64{3, 5} {
+		rs.SetReader(RT_RCDATA, ID(1), 0, size, open)
+		if err := rs.WriteObject(&bytes.Buffer{}, ArchAMD64); !isErr(err, errDataSizeMismatch) {
+			t.Error(size, err)
+		}
+		if rs.Get(RT_RCDATA, ID(1), 0) != nil {
+			t.Error(size)
+		}
+	}
+
+	rs.SetReader(RT_RCDATA, ID(1), 0, 4, func() (io.ReadCloser, error) {
+		return nil, errors.New(errRead)
+	})
+	if err := rs.WriteObject(&bytes.Buffer{}, ArchAMD64); !isErr(err, errRead) {
+		t.Error(err)
+	}
+}
+
+func TestResourceSet_SetCompressed(t *testing.T) {
+	data := bytes.Repeat([]byte("<p>Hello</p>"), 1000)
+
+	rs := &ResourceSet{}
+	if err := rs.SetCompressed(RT_RCDATA, Name("BUNDLE"), 0, data, compressed.Deflate); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.SetCompressed(Name("HTML"), ID(1), 0x409, data, compressed.Deflate); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.SetCompressed(ID(256), ID(1), 0, data, compressed.Deflate); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := rs.Get(RT_RCDATA, Name("BUNDLE"), 0)
+	if !compressed.IsCompressed(stored) || len(stored) >= len(data) {
+		t.Fail()
+	}
+	dec, err := compressed.Decode(stored)
+	if err != nil || !bytes.Equal(dec, data) {
+		t.Error(err)
+	}
+}
+
+func TestResourceSet_SetCompressed_Err(t *testing.T) {
+	rs := &ResourceSet{}
+	for _, typeID := range []Identifier{RT_HTML, RT_MANIFEST, RT_ICON, RT_STRING} {
+		if err := rs.SetCompressed(typeID, ID(1), 0, []byte("data"), compressed.Deflate); !isErr(err, errCannotCompressType) {
+			t.Error(typeID, err)
+		}
+	}
+	if err := rs.SetCompressed(RT_RCDATA, ID(1), 0, []byte("data"), 99); err == nil {
+		t.Fail()
+	}
+	if err := rs.SetCompressed(RT_RCDATA, ID(0), 0, []byte("data"), compressed.Deflate); !isErr(err, errZeroID) {
+		t.Error(err)
+	}
+	if rs.Count() != 0 {
+		t.Fail()
+	}
+}